@@ -2,202 +2,361 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"pharmacy-api/internal/config/config"
+	"pharmacy-api/internal/consumer"
+	"pharmacy-api/internal/events"
 	"pharmacy-api/internal/handlers"
+	"pharmacy-api/internal/logging"
+	"pharmacy-api/internal/metrics"
 	"pharmacy-api/internal/middleware"
+	"pharmacy-api/internal/migrations"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/outbox"
+	"pharmacy-api/internal/repositories"
+	"pharmacy-api/internal/repositories/memory"
 	postgres "pharmacy-api/internal/repositories/postgres" // Alias импорта
 	"pharmacy-api/internal/services"
+	"pharmacy-api/internal/tracing"
+	"pharmacy-api/pkg/database/migrate"
 	dbpkg "pharmacy-api/pkg/database/postgres" // Изменен импорт
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	//"github.com/segmentio/kafka-go"
-	"github.com/confluentinc/confluent-kafka-go/kafka"
-)
-
-const (
-	kafkaBrokersEnv     = "KAFKA_BROKERS"
-	kafkaTopicEnv       = "KAFKA_TOPIC"
-	kafkaGroupIDEnv     = "KAFKA_GROUP_ID"
-	kafkaLoginTopicEnv  = "KAFKA_LOGIN_TOPIC"
-	kafkaRegTopicEnv    = "KAFKA_REGISTRATION_TOPIC"
-	dbURL               = "DATABASE_URL"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
-	// Загрузка конфигурации из .env
-	config.LoadConfig()
+	// Загрузка конфигурации: значения по умолчанию, файл, окружение (.env) и флаги
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if len(args) > 0 && args[0] != "migrate" {
+		log.Fatalf("Unknown command %q\nusage: api [flags] [migrate <command>]\n%s", args[0], migrate.Usage)
+	}
+
+	// Логи в JSON; записи во время запроса содержат request_id, user_id и маршрут
+	if err := logging.Setup(os.Stdout, cfg.Log.Level, cfg.Log.Format); err != nil {
+		log.Fatalf("Invalid log settings: %v", err)
+	}
+	// Отладочные сообщения gin (маршруты при старте) - тоже через slog
+	gin.DebugPrintFunc = func(format string, values ...interface{}) {
+		slog.Debug("gin: " + strings.TrimSpace(fmt.Sprintf(format, values...)))
+	}
+
+	// Обработка сигналов завершения (Ctrl+C): ctx отменяется, и main переходит к упорядоченной остановке
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Подключение к базе данных
-	db, err := dbpkg.NewPostgresDB() // Использование функции из пакета
+	// Подключение к базе данных; пока она недоступна, подключение повторяется (DATABASE_CONNECT_TIMEOUT)
+	db, err := dbpkg.NewPostgresDB(ctx, cfg.Database.URL, cfg.Database.Options()) // Использование функции из пакета
 	if err != nil {
-		log.Fatalf("failed to connect database: %v", err)
+		fatal("Failed to connect to database", err)
+	}
+
+	// Подкоманда migrate: управление схемой без запуска сервера
+	migrator, err := migrations.New(db)
+	if err != nil {
+		fatal("Failed to load migrations", err)
+	}
+	if len(args) > 0 {
+		if err := migrator.Run(ctx, args[1:], os.Stdout); err != nil {
+			fatal("Migration failed", err)
+		}
 		return
 	}
-	log.Println("Database connection successful")
+
+	// Миграции при старте; advisory lock не дает нескольким репликам применять их одновременно
+	if cfg.Database.MigrateOnStart {
+		if err := migrator.Up(ctx); err != nil {
+			fatal("Failed to migrate database", err)
+		}
+	}
+
+	// Время запросов и состояние пула соединений для /metrics
+	if err := metrics.InstrumentGORM(db); err != nil {
+		fatal("Failed to instrument database", err)
+	}
+
+	// Трассировка OpenTelemetry: HTTP-запросы, запросы к базе, публикация и обработка событий
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Config())
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+	if err := tracing.InstrumentGORM(db); err != nil {
+		fatal("Failed to instrument database", err)
+	}
 
 	// Инициализация репозиториев
 	userRepository := postgres.NewUserRepository(db) // Использование postgres.NewUserRepository
 	medicineRepo := postgres.NewMedicineRepository(db) // Инициализируем репозиторий для лекарств
+	deliveryRepo := postgres.NewDeliveryRepository(db)
+	if err := metrics.RegisterInventory(medicineRepo, postgres.NewOutboxRepository(db)); err != nil {
+		fatal("Failed to register inventory metrics", err)
+	}
 
-	// Инициализация сервисов
-	authService := services.NewAuthService(userRepository)
-	medicineService := services.NewMedicineService(medicineRepo) // Инициализируем сервис для лекарств
+	// Проверки готовности: /readyz отвечает 503, пока зависимость недоступна
+	healthHandler := handlers.NewHealthHandler(cfg.Server.HealthCheckTimeout.Duration)
+	healthHandler.AddCheck("postgres", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
 
-	// Load Kafka Configuration (Consumer)
-	kafkaBrokers := strings.Split(os.Getenv(kafkaBrokersEnv), ",")
-	if len(kafkaBrokers) == 0 {
-		log.Fatalf("Error: %s environment variable not set", kafkaBrokersEnv)
+	// Публикация событий: брокер для локального запуска не нужен. Без брокеров события
+	// регистрации и входа отбрасываются, а события лекарств копятся в outbox до подключения Kafka
+	var publisher events.Publisher = events.NoopPublisher{}
+	if len(cfg.Kafka.Brokers) > 0 {
+		slog.Info("Publishing events to Kafka", "brokers", cfg.Kafka.Brokers)
+		kafkaPublisher := events.NewKafkaPublisher(cfg.Kafka.Brokers)
+		healthHandler.AddCheck("kafka", kafkaPublisher.Ping)
+		publisher = metrics.InstrumentPublisher(kafkaPublisher)
+	} else {
+		slog.Warn("Kafka brokers are not configured, events will not be published; medicine events stay in the outbox")
 	}
 
-	kafkaTopic := os.Getenv(kafkaTopicEnv)
-	if kafkaTopic == "" {
-		log.Fatalf("Error: %s environment variable not set", kafkaTopicEnv)
-	}
+	// Инициализация сервисов
+	authService := services.NewAuthService(userRepository, postgres.NewSigningKeyRepository(db), publisher, services.AuthConfig{
+		JWTSecret:         cfg.Auth.JWTSecret,
+		TokenTTL:          cfg.Auth.TokenTTL.Duration,
+		LoginTopic:        cfg.Kafka.LoginTopic,
+		RegistrationTopic: cfg.Kafka.RegistrationTopic,
+	})
+	transactor := postgres.NewTransactor(db)
+	medicineService := services.NewMedicineService(medicineRepo, transactor, cfg.Kafka.MedicineTopic) // Инициализируем сервис для лекарств
+	deliveryService := services.NewDeliveryService(deliveryRepo, transactor, cfg.Kafka.MedicineTopic)
+
+	// Фоновые задачи получают свои контексты: они останавливаются после HTTP-сервера, а не по сигналу
+	consumers := newWorkerGroup()
+	workers := newWorkerGroup()
 
-	kafkaGroupID := os.Getenv(kafkaGroupIDEnv)
-	if kafkaGroupID == "" {
-		kafkaGroupID = "my-group" // Установка значения по умолчанию
-		log.Printf("Using default Kafka group ID: %s", kafkaGroupID)
+	// Периодическая очистка корзины лекарств
+	if cfg.Trash.Retention.Duration > 0 {
+		workers.Go(func(ctx context.Context) {
+			services.RunTrashPurger(ctx, medicineService, cfg.Trash.Retention.Duration, cfg.Trash.PurgeInterval.Duration)
+		})
 	}
 
-	// Load Kafka Configuration (Producer)
-	kafkaLoginTopic := os.Getenv(kafkaLoginTopicEnv)
-	if kafkaLoginTopic == "" {
-		kafkaLoginTopic = "login-events" // Установка значения по умолчанию
-		log.Printf("Using default Kafka login topic: %s", kafkaLoginTopic)
+	// Ретранслятор outbox: отправляет в Kafka события, записанные сервисами вместе с изменениями.
+	// Без Kafka не запускается: сообщения отмечались бы отправленными и терялись
+	if len(cfg.Kafka.Brokers) > 0 {
+		outboxConfig := outbox.DefaultConfig
+		outboxConfig.PollInterval = cfg.Outbox.PollInterval.Duration
+		relay := outbox.NewRelay(transactor, publisher, outboxConfig)
+		workers.Go(relay.Run)
 	}
 
-	kafkaRegTopic := os.Getenv(kafkaRegTopicEnv)
-	if kafkaRegTopic == "" {
-		kafkaRegTopic = "registration-events" // Установка значения по умолчанию
-		log.Printf("Using default Kafka registration topic: %s", kafkaRegTopic)
+	// Ключи идемпотентности POST-запросов
+	var idempotencyStore repositories.IdempotencyStore
+	switch cfg.Idempotency.Store {
+	case "postgres":
+		idempotencyStore = postgres.NewIdempotencyStore(db)
+	case "memory":
+		idempotencyStore = memory.NewIdempotencyStore()
 	}
+	workers.Go(func(ctx context.Context) {
+		runCleanup(ctx, "idempotency keys", idempotencyStore.DeleteExpired, time.Hour)
+	})
+	idempotent := middleware.Idempotency(idempotencyStore, cfg.Idempotency.TTL.Duration)
 
-	// Create Kafka Producer
-	kafkaProducer, err := kafka.NewProducer(&kafka.ConfigMap{
-		"bootstrap.servers": strings.Join(kafkaBrokers, ","),
+	// Ограничение частоты запросов: в postgres лимиты общие для всех реплик
+	var rateLimitStore repositories.RateLimitStore
+	switch cfg.RateLimit.Store {
+	case "postgres":
+		rateLimitStore = postgres.NewRateLimitStore(db)
+	case "memory":
+		rateLimitStore = memory.NewRateLimitStore()
+	}
+	workers.Go(func(ctx context.Context) {
+		runCleanup(ctx, "rate limit buckets", rateLimitStore.DeleteExpired, 10*time.Minute)
 	})
-	if err != nil {
-		log.Fatalf("Failed to create Kafka producer: %s", err)
+	authLimit, ipLimit, apiLimit := noRateLimit, noRateLimit, noRateLimit
+	if cfg.RateLimit.Enabled {
+		// Вход и регистрация - строго и по IP: пользователь еще не известен
+		authLimit = middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicy{
+			Scope: "auth",
+			Read:  cfg.RateLimit.Auth.Limit(),
+			Write: cfg.RateLimit.Auth.Limit(),
+		})
+		// До проверки токена - по IP, чтобы запросы без токена или с неверным токеном тоже ограничивались
+		ipLimit = middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicy{
+			Scope: "ip",
+			Read:  cfg.RateLimit.IP.Limit(),
+			Write: cfg.RateLimit.IP.Limit(),
+		})
+		apiLimit = middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicy{
+			Scope: "api",
+			Read:  cfg.RateLimit.Read.Limit(),
+			Write: cfg.RateLimit.Write.Limit(),
+		})
 	}
-	defer kafkaProducer.Close()
 
-	// Создаем контекст с возможностью отмены
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// Обработка сигналов завершения (Ctrl+C)
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigChan
-		log.Println("Shutting down...")
-		cancel() // Отмена контекста для завершения consumer-а
-	}()
+	deliveryHandler := handlers.NewDeliveryHandler(deliveryService)
 
 	// Consumer
-	consumer := createKafkaConsumer(kafkaBrokers, kafkaTopic, kafkaGroupID)
-	if consumer != nil {  // Добавлена проверка на nil
-		defer func() {
-			if err := consumer.Close(); err != nil {
-				log.Printf("Error closing consumer: %v", err)
-			}
-		}()
+	if len(cfg.Kafka.Brokers) > 0 {
+		consumerConfig := consumer.DefaultConfig
+		consumerConfig.Brokers = cfg.Kafka.Brokers
+		consumerConfig.GroupID = cfg.Kafka.GroupID
+		consumerConfig.Topics = []string{cfg.Kafka.Topic, cfg.Kafka.DeliveryTopic}
+		consumerConfig.DeadLetterTopic = cfg.Kafka.DeadLetterTopic()
+		consumerConfig.Workers = cfg.Kafka.ConsumerWorkers
 
 		// Запускаем consumer в отдельной горутине
-		go consumeMessages(ctx, consumer)
+		eventConsumer := consumer.New(consumerConfig, publisher)
+		// Накладные склада обрабатываются по топику: склад может публиковать их простым JSON, без CloudEvents
+		eventConsumer.HandleTopic(cfg.Kafka.DeliveryTopic, deliveryHandler.HandleDeliveryEvent)
+		eventConsumer.Handle(services.DeliveryReceivedEvent, deliveryHandler.HandleDeliveryEvent)
+		consumers.Go(func(ctx context.Context) {
+			if err := eventConsumer.Run(ctx); err != nil {
+				slog.Error("Consumer failed", "error", err)
+			}
+		})
 	}
 
 	// Инициализация обработчиков
 	    // Инициализация обработчиков
-		medicineMedicineTopic := os.Getenv("KAFKA_MEDICINE_TOPIC")
-		authHandler := handlers.NewAuthHandler(authService, kafkaProducer, kafkaLoginTopic, kafkaRegTopic)
-		medicineHandler := handlers.NewMedicineHandler(medicineService, kafkaProducer, medicineMedicineTopic)// Инициализируем обработчик для лекарств
+		authHandler := handlers.NewAuthHandler(authService)
+		medicineHandler := handlers.NewMedicineHandler(medicineService)// Инициализируем обработчик для лекарств
 	
 	// Настройка Gin роутера
-	router := gin.Default()
+	router := gin.New() // без текстового логгера gin: запросы логирует RequestLogger
+	// X-Forwarded-For учитывается только от доверенных прокси, иначе клиент подменит свой IP для лимитов
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("Invalid trusted proxies", err)
+	}
 
 	// Middleware
-	router.Use(gin.Recovery())                         // Включаем recovery middleware
+	router.Use(middleware.RequestID())                 // ID запроса в ответе и во всех логах запроса
+	router.Use(middleware.RequestLogger())             // Одна запись в логе на запрос
+	router.Use(middleware.Recovery())                  // Паника -> 500; panic(http.ErrAbortHandler) обрывает соединение
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName)) // Спан запроса; продолжает трассу из traceparent
+	router.Use(middleware.Metrics())                   // Количество и длительность запросов для /metrics
 	router.Use(middleware.CORSMiddleware())           // Включаем CORS middleware
-	//router.Use(middleware.RequestLogger())
+	router.Use(middleware.CorrelationID())            // ID цепочки запросов для событий
+	router.Use(middleware.ErrorHandler())             // Ошибки обработчиков -> application/problem+json
+	router.Use(middleware.Timeout(cfg.Server.RequestTimeout.Duration, map[string]time.Duration{
+		// Импорт и экспорт каталога обрабатывают весь файл или всю таблицу
+		"/medicines/import": cfg.Server.BulkRequestTimeout.Duration,
+		"/medicines/export": cfg.Server.BulkRequestTimeout.Duration,
+	}))
+
+	// Служебные маршруты без аутентификации: проверки живости и готовности.
+	// Метрики (остатки и стоимость склада) отдаются на отдельном порту, см. metricsSrv
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
 
 	// Auth routes
 	authGroup := router.Group("/auth")
+	authGroup.Use(authLimit)
 	{
-		authGroup.POST("/register", authHandler.Register)
+		// Без открытой регистрации пользователей создает оператор (pharmacyctl create-user, create-admin)
+		if cfg.Auth.OpenRegistration {
+			authGroup.POST("/register", authHandler.Register)
+		}
 		authGroup.POST("/login", authHandler.Login)
 	}
 
 	// Medicine routes - подключение группы маршрутов для лекарств
     authorized := router.Group("/medicines")
-    authorized.Use(middleware.AuthMiddleware(authService))
+    authorized.Use(ipLimit, middleware.AuthMiddleware(authService), apiLimit)
     {
-        authorized.POST("/", medicineHandler.CreateMedicine)
+        authorized.POST("/", idempotent, medicineHandler.CreateMedicine)
+        authorized.POST("/import", idempotent, medicineHandler.ImportMedicines)
+        authorized.GET("/export", medicineHandler.ExportMedicines)
         authorized.GET("/:id", medicineHandler.GetMedicineByID)
         authorized.GET("/", medicineHandler.GetAllMedicines)
         authorized.PUT("/:id", medicineHandler.UpdateMedicine)
+        authorized.PATCH("/:id", medicineHandler.PatchMedicine)
         authorized.DELETE("/:id", medicineHandler.DeleteMedicine)
+        authorized.GET("/trash", medicineHandler.GetDeletedMedicines)
+        authorized.POST("/:id/restore", idempotent, medicineHandler.RestoreMedicine)
+        authorized.DELETE("/trash/:id", middleware.RequireRole(models.RoleAdmin), medicineHandler.PurgeMedicine)
     }
 
-	// Запуск сервера
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8082" // Значение по умолчанию
-	}
-	log.Printf("Starting server on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// Delivery routes - ручной разбор строк поставок, не сопоставленных с лекарствами
+	deliveries := router.Group("/deliveries")
+	deliveries.Use(ipLimit, middleware.AuthMiddleware(authService), apiLimit)
+	{
+		deliveries.GET("/unmatched", deliveryHandler.GetUnmatchedItems)
+		deliveries.POST("/unmatched/:id/resolve", idempotent, deliveryHandler.ResolveUnmatchedItem)
 	}
-}
 
-func createKafkaConsumer(kafkaBrokers []string, kafkaTopic string, kafkaGroupID string) *kafka.Consumer {
-	config := &kafka.ConfigMap{
-		"bootstrap.servers": strings.Join(kafkaBrokers, ","),
-		"group.id":          kafkaGroupID,
-		"auto.offset.reset": "earliest", // Или "latest", в зависимости от ваших потребностей
+	// Запуск сервера
+	port := cfg.Server.Port
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
-
-	consumer, err := kafka.NewConsumer(config)
-	if err != nil {
-		log.Fatalf("Failed to create Kafka consumer: %s", err)
-		return nil // Или обработайте ошибку другим способом
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metrics.Handler())
+	metricsSrv := &http.Server{
+		Addr:              ":" + cfg.Server.MetricsPort,
+		Handler:           metricsMux,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
+	serverErr := make(chan error, 2)
+	slog.Info("Starting server", "port", port, "metrics_port", cfg.Server.MetricsPort)
+	go serve(srv, serverErr)
+	go serve(metricsSrv, serverErr)
 
-	err = consumer.SubscribeTopics([]string{kafkaTopic}, nil)
-	if err != nil {
-		log.Fatalf("Failed to subscribe to topic: %s", err)
-		return nil // Или обработайте ошибку другим способом
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down")
+		stop()
+		// Сначала /readyz сообщает о выводе из балансировки, и только потом сервер перестает принимать запросы
+		healthHandler.SetDraining()
+		if delay := cfg.Server.DrainDelay.Duration; delay > 0 {
+			slog.Info("Draining before stopping the server", "delay", delay)
+			time.Sleep(delay)
+		}
+	case err := <-serverErr:
+		slog.Error("Failed to start server", "error", err)
+		exitCode = 1
 	}
-	return consumer
+	stop() // повторный сигнал завершит процесс сразу
+
+	shutdown([]*http.Server{srv, metricsSrv}, consumers, workers, publisher, shutdownTracing, db, cfg.Server.ShutdownTimeout.Duration)
+	os.Exit(exitCode)
 }
 
-func consumeMessages(ctx context.Context, consumer *kafka.Consumer) {
-	defer consumer.Close()
+// noRateLimit заменяет RateLimit, когда ограничение выключено
+func noRateLimit(c *gin.Context) {
+	c.Next()
+}
 
+// runCleanup периодически удаляет истекшие записи (ключи идемпотентности, корзины лимитов)
+func runCleanup(ctx context.Context, what string, deleteExpired func(context.Context, time.Time) (int64, error), interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Consumer stopped")
 			return
-		default:
-			msg, err := consumer.ReadMessage(-1) // Timeout -1 означает бесконечное ожидание
-			if err != nil {
-				if errors.Is(err, context.Canceled) {
-					log.Println("Consumer shutting down due to context cancellation")
-					return
-				}
-				log.Printf("Error reading message: %v", err)
-				continue
+		case <-ticker.C:
+			if n, err := deleteExpired(ctx, time.Now()); err != nil {
+				slog.ErrorContext(ctx, "Failed to delete expired "+what, "error", err)
+			} else if n > 0 {
+				slog.Info("Deleted expired "+what, "deleted", n)
 			}
-			fmt.Printf("Message on %s: %s\n", msg.TopicPartition, string(msg.Value))
-			consumer.CommitMessage(msg) // Подтверждаем получение сообщения
 		}
 	}
-}
\ No newline at end of file
+}
+
+// fatal логирует ошибку запуска и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
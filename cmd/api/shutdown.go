@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"pharmacy-api/internal/events"
+
+	"gorm.io/gorm"
+)
+
+// workerGroup - фоновые горутины с общим контекстом, которые останавливаются вместе
+type workerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newWorkerGroup() *workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workerGroup{ctx: ctx, cancel: cancel}
+}
+
+// Go запускает fn; fn должна завершиться после отмены переданного контекста
+func (g *workerGroup) Go(fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn(g.ctx)
+	}()
+}
+
+// Stop отменяет контекст и ждет завершения горутин, но не дольше, чем до отмены ctx
+func (g *workerGroup) Stop(ctx context.Context) error {
+	g.cancel()
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdown останавливает приложение по порядку: сначала перестает принимать запросы и дожидается
+// текущих, затем останавливает потребителей событий (они пишут в базу и публикуют события),
+// фоновые задачи, отправляет буферизованные события и спаны и в последнюю очередь закрывает базу.
+// Все этапы вместе ограничены timeout
+func shutdown(servers []*http.Server, consumers, workers *workerGroup, publisher events.Publisher, flushTraces func(context.Context) error, db *gorm.DB, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	slog.Info("Stopping HTTP servers")
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			slog.Error("HTTP server did not stop gracefully", "addr", srv.Addr, "error", err)
+			srv.Close()
+		}
+	}
+
+	slog.Info("Stopping consumers")
+	if err := consumers.Stop(ctx); err != nil {
+		slog.Error("Consumers did not stop in time", "error", err)
+	}
+
+	slog.Info("Stopping background workers")
+	if err := workers.Stop(ctx); err != nil {
+		slog.Error("Background workers did not stop in time", "error", err)
+	}
+
+	slog.Info("Flushing event publisher")
+	if err := publisher.Close(); err != nil {
+		slog.Error("Error closing event publisher", "error", err)
+	}
+
+	slog.Info("Flushing traces")
+	if err := flushTraces(ctx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+
+	slog.Info("Closing database")
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Error("Error closing database", "error", err)
+		}
+	}
+
+	slog.Info("Shutdown complete")
+}
+
+// serve запускает HTTP-сервер и возвращает ошибку, если сервер остановился не через Shutdown
+func serve(srv *http.Server, errc chan<- error) {
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errc <- err
+	}
+}
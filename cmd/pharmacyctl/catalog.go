@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"pharmacy-api/internal/catalog"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/services"
+)
+
+// importCatalog импортирует каталог из CSV/XLSX так же, как POST /medicines/import, и выводит отчет в JSON
+func importCatalog(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	path := fs.String("file", "", "CSV or XLSX file")
+	formatName := fs.String("format", "", "csv or xlsx (default: by file extension)")
+	mappingJSON := fs.String("mapping", "", `column mapping as JSON {"field": "column header"}`)
+	dryRun := fs.Bool("dry-run", false, "only report changes")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("-file is required")
+	}
+
+	var format catalog.Format
+	var err error
+	if *formatName != "" {
+		format, err = catalog.ParseFormat(*formatName)
+	} else {
+		format, err = catalog.FormatFromFilename(*path)
+	}
+	if err != nil {
+		return err
+	}
+	var mapping catalog.Mapping
+	if *mappingJSON != "" {
+		if err := json.Unmarshal([]byte(*mappingJSON), &mapping); err != nil {
+			return fmt.Errorf("invalid mapping: %w", err)
+		}
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	records, err := catalog.Read(file, format, mapping)
+	if err != nil {
+		return err
+	}
+
+	report, err := a.medicines.ImportMedicines(ctx, records, services.ImportOptions{DryRun: *dryRun})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// exportCatalog выгружает каталог с остатками в файл или stdout
+func exportCatalog(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	path := fs.String("file", "", "output file (default: stdout)")
+	formatName := fs.String("format", string(catalog.FormatCSV), "csv, xlsx or jsonl")
+	name := fs.String("name", "", "only medicines whose name contains this string")
+	barcode := fs.String("barcode", "", "only the medicine with this barcode")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	format, err := catalog.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if *path != "" {
+		if out, err = os.Create(*path); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	writer, err := catalog.NewWriter(out, format)
+	if err != nil {
+		return err
+	}
+	filter := models.MedicineFilter{Name: *name, Barcode: *barcode}
+	if err := a.medicines.StreamMedicines(ctx, filter, writer.Write); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if *path != "" {
+		return out.Close()
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// rotateKeys создает новый ключ подписи JWT. Реплики API начинают подписывать им токены
+// в течение минуты; токены, подписанные прежним ключом, принимаются до истечения их срока
+func rotateKeys(ctx context.Context, a *app, args []string) error {
+	if err := parseFlags(flag.NewFlagSet("rotate-keys", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	key, err := a.auth.RotateSigningKey(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("New signing key %s is active; tokens signed with previous keys are accepted for %s\n", key.KID, a.cfg.Auth.TokenTTL.Duration)
+	return nil
+}
+
+// listKeys выводит ключи подписи JWT
+func listKeys(ctx context.Context, a *app, args []string) error {
+	if err := parseFlags(flag.NewFlagSet("list-keys", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	keys, err := a.auth.SigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		fmt.Println("No signing keys; tokens are signed with JWT_SECRET")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tCREATED AT\tRETIRED AT")
+	for _, key := range keys {
+		retired := "active"
+		if key.RetiredAt != nil {
+			retired = key.RetiredAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key.KID, key.CreatedAt.UTC().Format("2006-01-02 15:04:05"), retired)
+	}
+	return w.Flush()
+}
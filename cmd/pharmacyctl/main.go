@@ -0,0 +1,144 @@
+// Команда pharmacyctl - инструменты оператора: пользователи и роли, миграции, каталог,
+// повторная отправка outbox и ротация ключей подписи JWT. Работает напрямую с базой
+// через сервисы и репозитории приложения; настройки те же, что у API (см. config.Load)
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gorm.io/gorm"
+
+	"pharmacy-api/internal/config/config"
+	"pharmacy-api/internal/events"
+	"pharmacy-api/internal/repositories"
+	"pharmacy-api/internal/repositories/postgres"
+	"pharmacy-api/internal/services"
+	dbpkg "pharmacy-api/pkg/database/postgres"
+)
+
+// command - подкоманда pharmacyctl
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, app *app, args []string) error
+}
+
+// commands - подкоманды в порядке вывода справки
+var commands = []command{
+	{"create-user", "-username NAME [-role user|admin]  create a user; the password is read from stdin", createUser},
+	{"create-admin", "-username NAME  create an administrator; the password is read from stdin", createAdmin},
+	{"reset-password", "-username NAME  set a new password read from stdin", resetPassword},
+	{"assign-role", "-username NAME -role user|admin  change a user's role", assignRole},
+	{"migrate", "up | down [N] | to <version> | status  manage the database schema", runMigrate},
+	{"import", "-file PATH [-format csv|xlsx] [-mapping JSON] [-dry-run]  import the catalog", importCatalog},
+	{"export", "[-file PATH] [-format csv|xlsx|jsonl] [-name S] [-barcode S]  export the catalog (stdout by default)", exportCatalog},
+	{"outbox-replay", "[-ids 1,2] [-topic T] [-since TIME] [-until TIME] [-all]  send outbox events again", replayOutbox},
+	{"rotate-keys", " create a new JWT signing key and retire the current one", rotateKeys},
+	{"list-keys", " list JWT signing keys", listKeys},
+}
+
+// app - зависимости подкоманд
+type app struct {
+	cfg       config.Config
+	db        *gorm.DB
+	auth      *services.AuthService
+	medicines services.MedicineService
+	outbox    repositories.OutboxRepository
+}
+
+func main() {
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := dbpkg.NewPostgresDB(ctx, cfg.Database.URL, cfg.Database.Options())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to connect database: %v\n", err)
+		os.Exit(1)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	// События регистрации публикуются сразу, остальные пишутся в outbox и отправляются API
+	var publisher events.Publisher = events.NoopPublisher{}
+	if len(cfg.Kafka.Brokers) > 0 {
+		publisher = events.NewKafkaPublisher(cfg.Kafka.Brokers)
+	}
+	defer publisher.Close()
+
+	transactor := postgres.NewTransactor(db)
+	a := &app{
+		cfg: cfg,
+		db:  db,
+		auth: services.NewAuthService(postgres.NewUserRepository(db), postgres.NewSigningKeyRepository(db), publisher, services.AuthConfig{
+			JWTSecret:         cfg.Auth.JWTSecret,
+			TokenTTL:          cfg.Auth.TokenTTL.Duration,
+			LoginTopic:        cfg.Kafka.LoginTopic,
+			RegistrationTopic: cfg.Kafka.RegistrationTopic,
+		}),
+		medicines: services.NewMedicineService(postgres.NewMedicineRepository(db), transactor, cfg.Kafka.MedicineTopic),
+		outbox:    postgres.NewOutboxRepository(db),
+	}
+
+	if err := cmd.run(ctx, a, args[1:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "pharmacyctl %s: %v\n", cmd.name, err)
+		}
+		stop()
+		publisher.Close()
+		os.Exit(1)
+	}
+}
+
+// findCommand ищет подкоманду по имени
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// usage выводит список подкоманд
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: pharmacyctl [config flags] <command> [command flags]")
+	fmt.Fprintln(os.Stderr, "Config flags and environment variables are the same as for the API (pharmacyctl -h lists them).")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+// parseFlags разбирает флаги подкоманды; лишние позиционные аргументы - ошибка
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+	return nil
+}
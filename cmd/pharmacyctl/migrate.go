@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"pharmacy-api/internal/migrations"
+)
+
+// runMigrate управляет схемой базы данных (см. migrate.Usage)
+func runMigrate(ctx context.Context, a *app, args []string) error {
+	migrator, err := migrations.New(a.db)
+	if err != nil {
+		return err
+	}
+	return migrator.Run(ctx, args, os.Stdout)
+}
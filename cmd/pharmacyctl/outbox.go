@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pharmacy-api/internal/models"
+)
+
+// replayOutbox снова ставит события outbox в очередь; их отправит ретранслятор работающего API
+func replayOutbox(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("outbox-replay", flag.ContinueOnError)
+	ids := fs.String("ids", "", "comma-separated message IDs")
+	topic := fs.String("topic", "", "only messages for this topic")
+	since := fs.String("since", "", "only messages created at or after this RFC 3339 time")
+	until := fs.String("until", "", "only messages created before this RFC 3339 time")
+	all := fs.Bool("all", false, "replay every message matching the other flags, even without -ids or -since")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var filter models.OutboxFilter
+	filter.Topic = *topic
+	for _, item := range strings.Split(*ids, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(item, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid message ID %q", item)
+		}
+		filter.IDs = append(filter.IDs, uint(id))
+	}
+	var err error
+	if filter.Since, err = parseTime("-since", *since); err != nil {
+		return err
+	}
+	if filter.Until, err = parseTime("-until", *until); err != nil {
+		return err
+	}
+	if len(filter.IDs) == 0 && filter.Since == nil && !*all {
+		return errors.New("pass -ids or -since to choose messages, or -all to replay the whole outbox")
+	}
+
+	n, err := a.outbox.Requeue(ctx, filter, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Requeued %d outbox messages; the API relay will send them on its next poll\n", n)
+	return nil
+}
+
+// parseTime разбирает необязательное время в формате RFC 3339
+func parseTime(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: expected RFC 3339 time like 2024-01-02T15:04:05Z, got %q", name, value)
+	}
+	return &t, nil
+}
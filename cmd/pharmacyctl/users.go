@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"pharmacy-api/internal/models"
+)
+
+// createUser создает пользователя с заданной ролью
+func createUser(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("create-user", flag.ContinueOnError)
+	username := fs.String("username", "", "user name")
+	role := fs.String("role", models.RoleUser, "role: user or admin")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	return createUserWithRole(ctx, a, *username, *role)
+}
+
+// createAdmin создает администратора: так заводится первый пользователь, когда открытая регистрация выключена
+func createAdmin(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := fs.String("username", "", "user name")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	return createUserWithRole(ctx, a, *username, models.RoleAdmin)
+}
+
+func createUserWithRole(ctx context.Context, a *app, username, role string) error {
+	if username == "" {
+		return errors.New("-username is required")
+	}
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	user, err := a.auth.CreateUser(ctx, username, password, role)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %s %q (id %d)\n", user.Role, user.Username, user.ID)
+	return nil
+}
+
+// resetPassword задает пользователю новый пароль
+func resetPassword(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	username := fs.String("username", "", "user name")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	if err := a.auth.ResetPassword(ctx, *username, password); err != nil {
+		return err
+	}
+	fmt.Printf("Password of %q has been reset\n", *username)
+	return nil
+}
+
+// assignRole меняет роль пользователя
+func assignRole(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("assign-role", flag.ContinueOnError)
+	username := fs.String("username", "", "user name")
+	role := fs.String("role", "", "role: user or admin")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *username == "" || *role == "" {
+		return errors.New("-username and -role are required")
+	}
+	if err := a.auth.AssignRole(ctx, *username, *role); err != nil {
+		return err
+	}
+	fmt.Printf("Role of %q is now %s; tokens issued earlier keep the old role until they expire\n", *username, *role)
+	return nil
+}
+
+// readPassword читает пароль: с терминала - без эха и с подтверждением,
+// иначе - первую строку stdin (echo "$PASSWORD" | pharmacyctl ...). В флагах пароль не передается,
+// чтобы он не попал в историю команд и список процессов
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	repeated, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if string(password) != string(repeated) {
+		return "", errors.New("passwords do not match")
+	}
+	return string(password), nil
+}
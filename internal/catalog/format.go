@@ -0,0 +1,61 @@
+// Package catalog читает и записывает каталог лекарств в форматах CSV, XLSX и JSON Lines
+package catalog
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Format - формат файла каталога
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatXLSX  Format = "xlsx"
+	FormatJSONL Format = "jsonl" // только экспорт
+)
+
+// Поля каталога, которые можно сопоставить с колонками файла
+const (
+	FieldName         = "name"
+	FieldDescription  = "description"
+	FieldBarcode      = "barcode"
+	FieldPrice        = "price"
+	FieldQuantity     = "quantity"
+	FieldReorderPoint = "reorder_point"
+)
+
+// Fields - все поддерживаемые поля в порядке колонок по умолчанию
+var Fields = []string{FieldName, FieldDescription, FieldBarcode, FieldPrice, FieldQuantity, FieldReorderPoint}
+
+// ParseFormat разбирает название формата
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(s))) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatXLSX:
+		return FormatXLSX, nil
+	case FormatJSONL:
+		return FormatJSONL, nil
+	}
+	return "", fmt.Errorf("unsupported format %q", s)
+}
+
+// FormatFromFilename определяет формат по расширению файла
+func FormatFromFilename(name string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	if ext == "" {
+		return "", fmt.Errorf("cannot detect format of %q", name)
+	}
+	return ParseFormat(ext)
+}
+
+func isKnownField(field string) bool {
+	for _, f := range Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
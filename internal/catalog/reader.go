@@ -0,0 +1,235 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Mapping сопоставляет поле каталога (name, price, ...) с заголовком колонки файла.
+// Пустое сопоставление означает, что заголовки совпадают с названиями полей
+type Mapping map[string]string
+
+// FieldError - ошибка в значении поля строки
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Record - строка файла каталога. Поля, которых нет в файле или которые пусты, равны nil
+type Record struct {
+	Line         int
+	Name         *string
+	Description  *string
+	Barcode      *string
+	Price        *float64
+	Quantity     *int
+	ReorderPoint *int
+	Errors       []FieldError
+}
+
+// Read читает все строки каталога; первая строка файла - заголовок
+func Read(r io.Reader, format Format, mapping Mapping) ([]Record, error) {
+	var rows [][]string
+	var err error
+	switch format {
+	case FormatCSV:
+		rows, err = readCSV(r)
+	case FormatXLSX:
+		rows, err = readXLSX(r)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	columns, err := resolveColumns(rows[0], mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		if isBlank(row) {
+			continue
+		}
+		records = append(records, parseRecord(i+2, row, columns))
+	}
+	return records, nil
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+	// Excel сохраняет CSV с BOM
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = detectDelimiter(br)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	return rows, nil
+}
+
+// detectDelimiter выбирает ';' или ',' по строке заголовка (русская локаль Excel использует ';')
+func detectDelimiter(br *bufio.Reader) rune {
+	head, _ := br.Peek(4096)
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	if bytes.Count(head, []byte{';'}) > bytes.Count(head, []byte{','}) {
+		return ';'
+	}
+	return ','
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("xlsx has no sheets")
+	}
+	rows, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %w", err)
+	}
+	return rows, nil
+}
+
+// resolveColumns возвращает индекс колонки для каждого найденного поля
+func resolveColumns(header []string, mapping Mapping) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, h := range header {
+		index[normalizeHeader(h)] = i
+	}
+
+	columns := make(map[string]int)
+	if len(mapping) == 0 {
+		for _, field := range Fields {
+			if i, ok := index[field]; ok {
+				columns[field] = i
+			}
+		}
+	} else {
+		// Ключи сортируются, чтобы ошибка в сопоставлении была одной и той же при каждом запуске
+		keys := make([]string, 0, len(mapping))
+		for field := range mapping {
+			keys = append(keys, field)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			column := mapping[key]
+			field := strings.ToLower(strings.TrimSpace(key))
+			if !isKnownField(field) {
+				return nil, fmt.Errorf("unknown field %q in mapping", field)
+			}
+			i, ok := index[normalizeHeader(column)]
+			if !ok {
+				return nil, fmt.Errorf("column %q mapped to %q not found", column, field)
+			}
+			columns[field] = i
+		}
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no known columns found, expected some of %s", strings.Join(Fields, ", "))
+	}
+	return columns, nil
+}
+
+// parseRecord разбирает строку; поля обходятся в порядке Fields, поэтому порядок ошибок постоянный
+func parseRecord(line int, row []string, columns map[string]int) Record {
+	rec := Record{Line: line}
+	for _, field := range Fields {
+		i, ok := columns[field]
+		if !ok || i >= len(row) {
+			continue
+		}
+		value := strings.TrimSpace(row[i])
+		if value == "" {
+			continue
+		}
+
+		switch field {
+		case FieldName:
+			rec.Name = &value
+		case FieldDescription:
+			rec.Description = &value
+		case FieldBarcode:
+			rec.Barcode = &value
+		case FieldPrice:
+			price, err := parsePrice(value)
+			if err != nil {
+				rec.Errors = append(rec.Errors, FieldError{Field: field, Message: err.Error()})
+				continue
+			}
+			rec.Price = &price
+		case FieldQuantity:
+			quantity, err := strconv.Atoi(value)
+			if err != nil {
+				rec.Errors = append(rec.Errors, FieldError{Field: field, Message: "must be an integer"})
+				continue
+			}
+			rec.Quantity = &quantity
+		case FieldReorderPoint:
+			reorderPoint, err := strconv.Atoi(value)
+			if err != nil {
+				rec.Errors = append(rec.Errors, FieldError{Field: field, Message: "must be an integer"})
+				continue
+			}
+			rec.ReorderPoint = &reorderPoint
+		}
+	}
+	return rec
+}
+
+// parsePrice разбирает цену с десятичной точкой или запятой ("12.50", "12,50").
+// Разделители разрядов не поддерживаются: "1,234.50" и "1.234,50" отклоняются, а не читаются неверно
+func parsePrice(value string) (float64, error) {
+	commas := strings.Count(value, ",")
+	if commas > 1 || (commas == 1 && strings.Contains(value, ".")) {
+		return 0, errors.New("must be a number with a single decimal separator and no thousands separators")
+	}
+	price, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil || math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0, errors.New("must be a number")
+	}
+	return price, nil
+}
+
+func normalizeHeader(h string) string {
+	return strings.ToLower(strings.TrimSpace(h))
+}
+
+func isBlank(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package catalog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "12.50", want: 12.5},
+		{value: "12,50", want: 12.5},
+		{value: "1,234", want: 1.234},
+		{value: "0", want: 0},
+		{value: "1,234.50", wantErr: true},
+		{value: "1.234,50", wantErr: true},
+		{value: "1,234,567", wantErr: true},
+		{value: "12 руб", wantErr: true},
+		{value: "NaN", wantErr: true},
+		{value: "Inf", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePrice(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePrice(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parsePrice(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		mapping Mapping
+		want    []Record
+		wantErr string
+	}{
+		{
+			name: "semicolon, BOM and decimal comma",
+			file: "\xEF\xBB\xBFName;Price;Quantity\nAspirin;12,50;10\n ; ;\nIbuprofen;;\n",
+			want: []Record{
+				{Line: 2, Name: ptr("Aspirin"), Price: ptr(12.5), Quantity: ptr(10)},
+				{Line: 4, Name: ptr("Ibuprofen")},
+			},
+		},
+		{
+			name:    "mapping",
+			file:    "Наименование,Цена\nAspirin,3\n",
+			mapping: Mapping{"name": "наименование", "Price": "Цена"},
+			want:    []Record{{Line: 2, Name: ptr("Aspirin"), Price: ptr(3.0)}},
+		},
+		{
+			name: "errors in field order",
+			file: "reorder_point,quantity,price,name\nx,y,\"1,234.5\",Aspirin\n",
+			want: []Record{{Line: 2, Name: ptr("Aspirin"), Errors: []FieldError{
+				{Field: FieldPrice, Message: "must be a number with a single decimal separator and no thousands separators"},
+				{Field: FieldQuantity, Message: "must be an integer"},
+				{Field: FieldReorderPoint, Message: "must be an integer"},
+			}}},
+		},
+		{
+			name:    "unknown mapped field",
+			file:    "a,b\n1,2\n",
+			mapping: Mapping{"weight": "a", "color": "b"},
+			wantErr: `unknown field "color" in mapping`,
+		},
+		{
+			name:    "no known columns",
+			file:    "a,b\n1,2\n",
+			wantErr: "no known columns found",
+		},
+		{
+			name:    "empty file",
+			file:    "",
+			wantErr: "file is empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.file), FormatCSV, tt.mapping)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Read() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
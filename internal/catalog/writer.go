@@ -0,0 +1,161 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"pharmacy-api/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// exportHeader - колонки экспорта; названия совпадают с полями импорта,
+// поэтому выгруженный файл можно загрузить обратно
+var exportHeader = []string{"id", FieldName, FieldDescription, FieldBarcode, FieldPrice, FieldQuantity, FieldReorderPoint, "updated_at"}
+
+// Writer построчно записывает каталог. Close дописывает файл и должен быть вызван в конце
+type Writer interface {
+	Write(medicine models.Medicine) error
+	Close() error
+}
+
+// NewWriter создает Writer для заданного формата
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	case FormatJSONL:
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// ContentType возвращает MIME-тип формата
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	}
+	return "application/octet-stream"
+}
+
+func exportRow(m models.Medicine) []string {
+	return []string{
+		strconv.FormatUint(uint64(m.ID), 10),
+		m.Name,
+		m.Description,
+		m.Barcode,
+		strconv.FormatFloat(m.Price, 'f', -1, 64),
+		strconv.Itoa(m.Quantity),
+		strconv.Itoa(m.ReorderPoint),
+		m.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	// BOM, чтобы Excel правильно открыл кириллицу
+	if _, err := w.Write([]byte{0xEF, 0xBB, 0xBF}); err != nil {
+		return nil, err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportHeader); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (c *csvWriter) Write(m models.Medicine) error {
+	return c.w.Write(exportRow(m))
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// xlsxWriter пишет строки через StreamWriter excelize, который сбрасывает данные
+// во временный файл, а не держит весь лист в памяти. Сам файл XLSX - zip-архив,
+// поэтому клиенту он отдается целиком в Close
+type xlsxWriter struct {
+	out  io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	row  int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	sw, err := file.NewStreamWriter(file.GetSheetName(0))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	x := &xlsxWriter{out: w, file: file, sw: sw}
+	if err := x.writeRow(toCells(exportHeader)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) Write(m models.Medicine) error {
+	row := toCells(exportRow(m))
+	// Числа записываем числами, чтобы с ними можно было считать в Excel
+	row[0] = m.ID
+	row[4] = m.Price
+	row[5] = m.Quantity
+	row[6] = m.ReorderPoint
+	return x.writeRow(row)
+}
+
+func (x *xlsxWriter) writeRow(values []interface{}) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.sw.SetRow(cell, values)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.out)
+	return err
+}
+
+func toCells(values []string) []interface{} {
+	cells := make([]interface{}, len(values))
+	for i, v := range values {
+		cells[i] = v
+	}
+	return cells
+}
+
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(m models.Medicine) error {
+	return j.enc.Encode(m) // Encode добавляет перевод строки
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"pharmacy-api/internal/models"
+)
+
+func exportSample() []models.Medicine {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	medicines := []models.Medicine{
+		{Name: "Аспирин", Description: "От боли; \"шипучий\"", Barcode: "4600000000001", Price: 99.5, Quantity: 10, ReorderPoint: 5},
+		{Name: "Ibuprofen, 200 mg", Price: 150, Quantity: 0},
+	}
+	for i := range medicines {
+		medicines[i].ID = uint(i + 1)
+		medicines[i].UpdatedAt = updated
+	}
+	return medicines
+}
+
+func export(t *testing.T, format Format, medicines []models.Medicine) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range medicines {
+		if err := w.Write(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Выгруженный файл загружается обратно без сопоставления колонок и без потерь
+func TestExportImportRoundTrip(t *testing.T) {
+	medicines := exportSample()
+	var want []Record
+	for i, m := range medicines {
+		rec := Record{Line: i + 2, Name: ptr(m.Name), Price: ptr(m.Price), Quantity: ptr(m.Quantity), ReorderPoint: ptr(m.ReorderPoint)}
+		if m.Description != "" {
+			rec.Description = ptr(m.Description)
+		}
+		if m.Barcode != "" {
+			rec.Barcode = ptr(m.Barcode)
+		}
+		want = append(want, rec)
+	}
+
+	for _, format := range []Format{FormatCSV, FormatXLSX} {
+		t.Run(string(format), func(t *testing.T) {
+			got, err := Read(bytes.NewReader(export(t, format, medicines)), format, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	medicines := exportSample()
+	scanner := bufio.NewScanner(bytes.NewReader(export(t, FormatJSONL, medicines)))
+	var got []models.Medicine
+	for scanner.Scan() {
+		var m models.Medicine
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatalf("line %d: %v", len(got)+1, err)
+		}
+		got = append(got, m)
+	}
+	if len(got) != len(medicines) {
+		t.Fatalf("got %d lines, want %d", len(got), len(medicines))
+	}
+	for i := range got {
+		if got[i].ID != medicines[i].ID || got[i].Name != medicines[i].Name || got[i].Price != medicines[i].Price {
+			t.Errorf("line %d = %+v, want %+v", i+1, got[i], medicines[i])
+		}
+	}
+}
+
+func TestExportEmptyCatalog(t *testing.T) {
+	for _, format := range []Format{FormatCSV, FormatXLSX} {
+		records, err := Read(bytes.NewReader(export(t, format, nil)), format, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(records) != 0 {
+			t.Errorf("%s: got %d records from an empty export", format, len(records))
+		}
+	}
+}
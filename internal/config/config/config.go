@@ -1,19 +1,389 @@
+// Package config - настройки приложения. Config загружается один раз при старте (см. Load)
+// и передается в конструкторы явно; остальной код переменные окружения не читает
 package config
 
-//Код config.LoadConfig() пытается загрузить конфигурацию из файла .env. Если это не удается (например, файл .env отсутствует),
-//и при этом приложение не запущено в production-окружении, то в консоль выводится сообщение об ошибке. 
-
-
 import (
-    "log"
-    "os"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
-    "github.com/joho/godotenv"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/tracing"
+	dbpkg "pharmacy-api/pkg/database/postgres"
 )
 
-func LoadConfig() {
-    err := godotenv.Load()
-    if err != nil && os.Getenv("ENVIRONMENT") != "production" {
-        log.Println("Error loading .env file") // Не критическая ошибка, если в production используются переменные окружения
-    }
-}
\ No newline at end of file
+// Config - все настройки приложения.
+// Тег env - переменная окружения; из нее же получается имя флага (DATABASE_URL -> -database-url)
+type Config struct {
+	Server      ServerConfig      `yaml:"server" toml:"server"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database"`
+	Auth        AuthConfig        `yaml:"auth" toml:"auth"`
+	Kafka       KafkaConfig       `yaml:"kafka" toml:"kafka"`
+	Outbox      OutboxConfig      `yaml:"outbox" toml:"outbox"`
+	Trash       TrashConfig       `yaml:"trash" toml:"trash"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" toml:"idempotency"`
+	Tracing     TracingConfig     `yaml:"tracing" toml:"tracing"`
+	Log         LogConfig         `yaml:"log" toml:"log"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" toml:"rate_limit"`
+}
+
+// ServerConfig - HTTP-сервер
+type ServerConfig struct {
+	Port               string   `yaml:"port" toml:"port" env:"PORT" usage:"HTTP port"`
+	MetricsPort        string   `yaml:"metrics_port" toml:"metrics_port" env:"METRICS_PORT" usage:"port of the admin listener serving /metrics; keep it off the public network"`
+	ShutdownTimeout    Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" usage:"how long to wait for in-flight work on shutdown"`
+	DrainDelay         Duration `yaml:"drain_delay" toml:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY" usage:"how long /readyz reports draining before the server stops accepting requests"`
+	HealthCheckTimeout Duration `yaml:"health_check_timeout" toml:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT" usage:"timeout of each dependency check in /readyz"`
+	RequestTimeout     Duration `yaml:"request_timeout" toml:"request_timeout" env:"REQUEST_TIMEOUT" usage:"cancel requests running longer than this; 0 disables"`
+	BulkRequestTimeout Duration `yaml:"bulk_request_timeout" toml:"bulk_request_timeout" env:"BULK_REQUEST_TIMEOUT" usage:"timeout of catalog import and export; 0 disables"`
+	TrustedProxies     []string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES" usage:"comma-separated proxy IPs or CIDRs whose X-Forwarded-For is trusted for the client IP"`
+}
+
+// DatabaseConfig - подключение к PostgreSQL
+type DatabaseConfig struct {
+	URL             string   `yaml:"url" toml:"url" env:"DATABASE_URL" usage:"PostgreSQL connection string"`
+	MigrateOnStart  bool     `yaml:"migrate_on_start" toml:"migrate_on_start" env:"DATABASE_MIGRATE_ON_START" usage:"apply pending migrations before serving"`
+	MaxOpenConns    int      `yaml:"max_open_conns" toml:"max_open_conns" env:"DATABASE_MAX_OPEN_CONNS" usage:"maximum open connections; 0 means unlimited"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DATABASE_MAX_IDLE_CONNS" usage:"maximum idle connections kept in the pool"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DATABASE_CONN_MAX_LIFETIME" usage:"close connections older than this; 0 keeps them"`
+	ConnMaxIdleTime Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DATABASE_CONN_MAX_IDLE_TIME" usage:"close connections idle longer than this; 0 keeps them"`
+	LogLevel        string   `yaml:"log_level" toml:"log_level" env:"DATABASE_LOG_LEVEL" usage:"SQL log level: silent, error, warn or info"`
+	SlowThreshold   Duration `yaml:"slow_threshold" toml:"slow_threshold" env:"DATABASE_SLOW_QUERY_THRESHOLD" usage:"log queries slower than this; 0 disables"`
+	ConnectTimeout  Duration `yaml:"connect_timeout" toml:"connect_timeout" env:"DATABASE_CONNECT_TIMEOUT" usage:"how long to retry connecting on startup; 0 tries once"`
+}
+
+// AuthConfig - аутентификация
+type AuthConfig struct {
+	JWTSecret        string   `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET" usage:"secret for signing JWT"`
+	TokenTTL         Duration `yaml:"token_ttl" toml:"token_ttl" env:"JWT_TOKEN_TTL" usage:"lifetime of issued tokens"`
+	OpenRegistration bool     `yaml:"open_registration" toml:"open_registration" env:"AUTH_OPEN_REGISTRATION" usage:"allow anyone to register via POST /auth/register"`
+}
+
+// KafkaConfig - брокер событий. Без брокеров события не публикуются и не потребляются
+type KafkaConfig struct {
+	Brokers           []string `yaml:"brokers" toml:"brokers" env:"KAFKA_BROKERS" usage:"comma-separated Kafka brokers; empty disables Kafka"`
+	Topic             string   `yaml:"topic" toml:"topic" env:"KAFKA_TOPIC" usage:"topic consumed by the application"`
+	GroupID           string   `yaml:"group_id" toml:"group_id" env:"KAFKA_GROUP_ID" usage:"consumer group"`
+	LoginTopic        string   `yaml:"login_topic" toml:"login_topic" env:"KAFKA_LOGIN_TOPIC" usage:"topic for login events"`
+	RegistrationTopic string   `yaml:"registration_topic" toml:"registration_topic" env:"KAFKA_REGISTRATION_TOPIC" usage:"topic for registration events"`
+	MedicineTopic     string   `yaml:"medicine_topic" toml:"medicine_topic" env:"KAFKA_MEDICINE_TOPIC" usage:"topic for medicine events; empty disables them"`
+	DeliveryTopic     string   `yaml:"delivery_topic" toml:"delivery_topic" env:"KAFKA_DELIVERY_TOPIC" usage:"topic with warehouse delivery notes"`
+	DLQTopic          string   `yaml:"dlq_topic" toml:"dlq_topic" env:"KAFKA_DLQ_TOPIC" usage:"dead-letter topic (default <topic>.dlq)"`
+	ConsumerWorkers   int      `yaml:"consumer_workers" toml:"consumer_workers" env:"KAFKA_CONSUMER_WORKERS" usage:"partitions processed in parallel"`
+}
+
+// OutboxConfig - ретранслятор outbox
+type OutboxConfig struct {
+	PollInterval Duration `yaml:"poll_interval" toml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" usage:"pause between outbox polls"`
+}
+
+// TrashConfig - автоочистка корзины лекарств
+type TrashConfig struct {
+	Retention     Duration `yaml:"retention" toml:"retention" env:"MEDICINE_TRASH_RETENTION" usage:"how long deleted medicines are kept; 0 disables purging"`
+	PurgeInterval Duration `yaml:"purge_interval" toml:"purge_interval" env:"MEDICINE_TRASH_PURGE_INTERVAL" usage:"how often the trash is purged"`
+}
+
+// IdempotencyConfig - ключи идемпотентности POST-запросов
+type IdempotencyConfig struct {
+	Store string   `yaml:"store" toml:"store" env:"IDEMPOTENCY_STORE" usage:"postgres or memory"`
+	TTL   Duration `yaml:"ttl" toml:"ttl" env:"IDEMPOTENCY_TTL" usage:"how long responses are kept for replay"`
+}
+
+// TracingConfig - трассировка OpenTelemetry
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER" usage:"span exporter: none, stdout (written to stderr) or otlp"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT" usage:"OTLP/HTTP collector URL (default http://localhost:4318)"`
+	ServiceName  string  `yaml:"service_name" toml:"service_name" env:"TRACING_SERVICE_NAME" usage:"service.name of recorded spans"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"fraction of new traces recorded, from 0 to 1"`
+}
+
+// LogConfig - логи приложения
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL" usage:"minimum log level: debug, info, warn or error"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT" usage:"log format: json or text"`
+}
+
+// RateLimitConfig - ограничение частоты запросов
+type RateLimitConfig struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled" env:"RATE_LIMIT_ENABLED" usage:"limit request rate per client"`
+	Store   string `yaml:"store" toml:"store" env:"RATE_LIMIT_STORE" usage:"memory (per instance) or postgres (shared by replicas)"`
+	Auth    Rate   `yaml:"auth" toml:"auth" env:"RATE_LIMIT_AUTH" usage:"login and registration attempts per client IP, e.g. 10/1m"`
+	IP      Rate   `yaml:"ip" toml:"ip" env:"RATE_LIMIT_IP" usage:"requests to authenticated routes per client IP, counted before the token is checked; must cover all users behind one NAT"`
+	Read    Rate   `yaml:"read" toml:"read" env:"RATE_LIMIT_READ" usage:"GET requests per user"`
+	Write   Rate   `yaml:"write" toml:"write" env:"RATE_LIMIT_WRITE" usage:"other requests per user"`
+}
+
+// Default возвращает настройки по умолчанию
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:               "8082",
+			MetricsPort:        "9090",
+			ShutdownTimeout:    Duration{30 * time.Second},
+			DrainDelay:         Duration{5 * time.Second},
+			HealthCheckTimeout: Duration{2 * time.Second},
+			RequestTimeout:     Duration{30 * time.Second},
+			BulkRequestTimeout: Duration{5 * time.Minute},
+		},
+		Database: DatabaseConfig{
+			MigrateOnStart:  true,
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: Duration{30 * time.Minute},
+			ConnMaxIdleTime: Duration{5 * time.Minute},
+			LogLevel:        "warn",
+			SlowThreshold:   Duration{200 * time.Millisecond},
+			ConnectTimeout:  Duration{time.Minute},
+		},
+		Auth: AuthConfig{
+			TokenTTL:         Duration{time.Hour},
+			OpenRegistration: true,
+		},
+		Kafka: KafkaConfig{
+			GroupID:           "my-group",
+			LoginTopic:        "login-events",
+			RegistrationTopic: "registration-events",
+			DeliveryTopic:     "warehouse-deliveries",
+			ConsumerWorkers:   4,
+		},
+		Outbox: OutboxConfig{
+			PollInterval: Duration{time.Second},
+		},
+		Trash: TrashConfig{
+			Retention:     Duration{30 * 24 * time.Hour},
+			PurgeInterval: Duration{time.Hour},
+		},
+		Idempotency: IdempotencyConfig{
+			Store: "postgres",
+			TTL:   Duration{24 * time.Hour},
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "pharmacy-api",
+			SampleRatio: 1,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   "memory",
+			Auth:    Rate{Requests: 10, Period: time.Minute},
+			IP:      Rate{Requests: 1000, Period: time.Minute},
+			Read:    Rate{Requests: 300, Period: time.Minute},
+			Write:   Rate{Requests: 60, Period: time.Minute},
+		},
+	}
+}
+
+// Validate проверяет настройки и возвращает все ошибки сразу
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		add("PORT: must be a number between 1 and 65535, got %q", c.Server.Port)
+	}
+	if port, err := strconv.Atoi(c.Server.MetricsPort); err != nil || port < 1 || port > 65535 {
+		add("METRICS_PORT: must be a number between 1 and 65535, got %q", c.Server.MetricsPort)
+	} else if c.Server.MetricsPort == c.Server.Port {
+		add("METRICS_PORT: must differ from PORT")
+	}
+	if c.Server.ShutdownTimeout.Duration <= 0 {
+		add("SHUTDOWN_TIMEOUT: must be positive")
+	}
+	if c.Server.DrainDelay.Duration < 0 {
+		add("SHUTDOWN_DRAIN_DELAY: must not be negative")
+	}
+	if c.Server.HealthCheckTimeout.Duration <= 0 {
+		add("HEALTH_CHECK_TIMEOUT: must be positive")
+	}
+	if c.Server.RequestTimeout.Duration < 0 {
+		add("REQUEST_TIMEOUT: must not be negative")
+	}
+	if c.Server.BulkRequestTimeout.Duration < 0 {
+		add("BULK_REQUEST_TIMEOUT: must not be negative")
+	}
+	if c.Database.URL == "" {
+		add("DATABASE_URL: is required")
+	}
+	if c.Database.MaxOpenConns < 0 {
+		add("DATABASE_MAX_OPEN_CONNS: must not be negative")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		add("DATABASE_MAX_IDLE_CONNS: must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		add("DATABASE_MAX_IDLE_CONNS: must not exceed DATABASE_MAX_OPEN_CONNS")
+	}
+	if c.Database.ConnMaxLifetime.Duration < 0 {
+		add("DATABASE_CONN_MAX_LIFETIME: must not be negative")
+	}
+	if c.Database.ConnMaxIdleTime.Duration < 0 {
+		add("DATABASE_CONN_MAX_IDLE_TIME: must not be negative")
+	}
+	switch c.Database.LogLevel {
+	case "silent", "error", "warn", "info":
+	default:
+		add("DATABASE_LOG_LEVEL: must be silent, error, warn or info, got %q", c.Database.LogLevel)
+	}
+	if c.Database.SlowThreshold.Duration < 0 {
+		add("DATABASE_SLOW_QUERY_THRESHOLD: must not be negative")
+	}
+	if c.Database.ConnectTimeout.Duration < 0 {
+		add("DATABASE_CONNECT_TIMEOUT: must not be negative")
+	}
+	if c.Auth.JWTSecret == "" {
+		add("JWT_SECRET: is required")
+	}
+	if c.Auth.TokenTTL.Duration <= 0 {
+		add("JWT_TOKEN_TTL: must be positive")
+	}
+	if len(c.Kafka.Brokers) > 0 {
+		if c.Kafka.Topic == "" {
+			add("KAFKA_TOPIC: is required when KAFKA_BROKERS is set")
+		}
+		if c.Kafka.GroupID == "" {
+			add("KAFKA_GROUP_ID: is required when KAFKA_BROKERS is set")
+		}
+	}
+	if c.Kafka.ConsumerWorkers < 1 {
+		add("KAFKA_CONSUMER_WORKERS: must be at least 1")
+	}
+	if c.Outbox.PollInterval.Duration <= 0 {
+		add("OUTBOX_POLL_INTERVAL: must be positive")
+	}
+	if c.Trash.Retention.Duration < 0 {
+		add("MEDICINE_TRASH_RETENTION: must not be negative")
+	}
+	if c.Trash.PurgeInterval.Duration <= 0 {
+		add("MEDICINE_TRASH_PURGE_INTERVAL: must be positive")
+	}
+	switch c.Idempotency.Store {
+	case "postgres", "memory":
+	default:
+		add("IDEMPOTENCY_STORE: must be postgres or memory, got %q", c.Idempotency.Store)
+	}
+	if c.Idempotency.TTL.Duration <= 0 {
+		add("IDEMPOTENCY_TTL: must be positive")
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		add("TRACING_EXPORTER: must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.ServiceName == "" {
+		add("TRACING_SERVICE_NAME: is required")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("TRACING_SAMPLE_RATIO: must be between 0 and 1")
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		add("LOG_LEVEL: must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		add("LOG_FORMAT: must be json or text, got %q", c.Log.Format)
+	}
+	switch c.RateLimit.Store {
+	case "postgres", "memory":
+	default:
+		add("RATE_LIMIT_STORE: must be postgres or memory, got %q", c.RateLimit.Store)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Options возвращает настройки подключения для dbpkg.NewPostgresDB
+func (c DatabaseConfig) Options() dbpkg.Options {
+	return dbpkg.Options{
+		MaxOpenConns:    c.MaxOpenConns,
+		MaxIdleConns:    c.MaxIdleConns,
+		ConnMaxLifetime: c.ConnMaxLifetime.Duration,
+		ConnMaxIdleTime: c.ConnMaxIdleTime.Duration,
+		LogLevel:        c.LogLevel,
+		SlowThreshold:   c.SlowThreshold.Duration,
+		ConnectTimeout:  c.ConnectTimeout.Duration,
+	}
+}
+
+// Config возвращает настройки для tracing.Setup
+func (c TracingConfig) Config() tracing.Config {
+	return tracing.Config{
+		Exporter:    c.Exporter,
+		Endpoint:    c.OTLPEndpoint,
+		ServiceName: c.ServiceName,
+		SampleRatio: c.SampleRatio,
+	}
+}
+
+// DeadLetterTopic возвращает dead-letter топик потребителя
+func (c KafkaConfig) DeadLetterTopic() string {
+	if c.DLQTopic != "" {
+		return c.DLQTopic
+	}
+	return c.Topic + ".dlq"
+}
+
+// Rate - частота запросов "<запросов>/<период>": "10/1m", "300/1m", "5/1s".
+// Сразу можно сделать <запросов> запросов, затем корзина пополняется равномерно за <период>
+type Rate struct {
+	Requests int
+	Period   time.Duration
+}
+
+// UnmarshalText разбирает частоту
+func (r *Rate) UnmarshalText(text []byte) error {
+	requests, period, ok := strings.Cut(string(text), "/")
+	if !ok {
+		return fmt.Errorf("invalid rate %q, expected <requests>/<period> such as 10/1m", text)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid number of requests in rate %q", text)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid period in rate %q", text)
+	}
+	r.Requests, r.Period = n, d
+	return nil
+}
+
+// MarshalText выводит частоту
+func (r Rate) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(r.Requests) + "/" + r.Period.String()), nil
+}
+
+// Limit возвращает лимит token bucket
+func (r Rate) Limit() models.RateLimit {
+	return models.RateLimit{Rate: float64(r.Requests) / r.Period.Seconds(), Burst: r.Requests}
+}
+
+// Duration - длительность в формате time.ParseDuration ("30s", "1h") в файле, окружении и флагах
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText разбирает длительность
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalText выводит длительность
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileEnv - переменная окружения с путем к файлу настроек (как и флаг -config)
+const configFileEnv = "CONFIG_FILE"
+
+// Load собирает настройки из источников по возрастанию приоритета: значения по умолчанию,
+// файл YAML/TOML (-config или CONFIG_FILE), переменные окружения (и файл .env), флаги командной строки.
+// Ошибки разбора и проверки возвращаются все вместе. Аргументы после флагов (подкоманда) возвращаются как есть
+func Load(args []string) (Config, []string, error) {
+	// Код пытается загрузить переменные из файла .env. Если это не удается (например, файл .env отсутствует),
+	// и при этом приложение не запущено в production-окружении, то в консоль выводится сообщение об ошибке
+	if err := godotenv.Load(); err != nil && os.Getenv("ENVIRONMENT") != "production" {
+		slog.Info("No .env file loaded", "error", err) // Не критическая ошибка, если в production используются переменные окружения
+	}
+
+	cfg := Default()
+	fields := settings(&cfg)
+
+	// Флаги разбираются первыми, чтобы узнать путь к файлу, но применяются последними
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(configFileEnv), "path to a YAML or TOML config file")
+	flagValues := make(map[string]string)
+	for _, f := range fields {
+		name := f.flag
+		fs.Func(name, f.usage+" (env "+f.env+")", func(value string) error {
+			flagValues[name] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if value, ok := os.LookupEnv(f.env); ok && value != "" {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+			}
+		}
+	}
+	for _, f := range fields {
+		if value, ok := flagValues[f.flag]; ok {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", f.flag, err))
+			}
+		}
+	}
+	if err := errors.Join(append(errs, cfg.Validate())...); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile читает файл настроек; формат определяется по расширению
+func loadFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	var decode func(io.Reader, *Config) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decode = func(r io.Reader, cfg *Config) error {
+			dec := yaml.NewDecoder(r)
+			dec.KnownFields(true)
+			return dec.Decode(cfg)
+		}
+	case ".toml":
+		decode = func(r io.Reader, cfg *Config) error {
+			return toml.NewDecoder(r).DisallowUnknownFields().Decode(cfg)
+		}
+	default:
+		return fmt.Errorf("unsupported config file format %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+
+	if err := decode(f, cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// setting - поле Config, которое задается переменной окружения и флагом
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	value reflect.Value
+}
+
+// settings собирает поля с тегом env
+func settings(cfg *Config) []setting {
+	var result []setting
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			env := field.Tag.Get("env")
+			if env == "" {
+				if field.Type.Kind() == reflect.Struct {
+					walk(v.Field(i))
+				}
+				continue
+			}
+			result = append(result, setting{
+				env:   env,
+				flag:  strings.ReplaceAll(strings.ToLower(env), "_", "-"),
+				usage: field.Tag.Get("usage"),
+				value: v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem())
+	return result
+}
+
+// set разбирает строковое значение по типу поля
+func (s setting) set(value string) error {
+	if u, ok := s.value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		s.value.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		s.value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		s.value.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
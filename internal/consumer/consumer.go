@@ -0,0 +1,293 @@
+// Package consumer читает события из Kafka и передает их обработчикам, зарегистрированным по топику или типу события
+package consumer
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"pharmacy-api/internal/events"
+	"pharmacy-api/internal/logging"
+	"pharmacy-api/internal/tracing"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler обрабатывает одно событие. Ошибка приводит к повторной попытке,
+// кроме ошибок, обернутых в Permanent: такие сообщения сразу уходят в dead-letter топик
+type Handler func(ctx context.Context, message Message) error
+
+// permanentError - ошибка, которую не исправит повторная попытка (некорректные данные)
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку как неисправимую повторной попыткой
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Config - параметры потребителя
+type Config struct {
+	Brokers         []string
+	GroupID         string
+	Topics          []string
+	DeadLetterTopic string        // пустой - сообщения, которые не удалось обработать, только логируются
+	Workers         int           // сколько партиций обрабатывается параллельно
+	MaxAttempts     int           // попыток обработки до отправки в dead-letter топик
+	MinBackoff      time.Duration // задержка перед первой повторной попыткой
+	MaxBackoff      time.Duration // максимальная задержка между попытками
+}
+
+// DefaultConfig - параметры по умолчанию (без брокеров, группы и топиков)
+var DefaultConfig = Config{
+	Workers:     4,
+	MaxAttempts: 5,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  30 * time.Second,
+}
+
+// commitTimeout ограничивает фиксацию смещения, которая выполняется и во время остановки
+const commitTimeout = 5 * time.Second
+
+// Consumer читает сообщения группой потребителей Kafka.
+// Сообщения одной партиции обрабатываются одним воркером по порядку, смещение фиксируется
+// только после успешной обработки или отправки в dead-letter топик (at-least-once)
+type Consumer struct {
+	cfg       Config
+	reader    *kafka.Reader
+	publisher events.Publisher
+	handlers  map[string]Handler // по типу события
+	topics    map[string]Handler // по топику, независимо от формата и типа сообщения
+}
+
+// New создает новый экземпляр Consumer; publisher используется для dead-letter топика
+func New(cfg Config, publisher events.Publisher) *Consumer {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	return &Consumer{
+		cfg: cfg,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     cfg.Brokers,
+			GroupID:     cfg.GroupID,
+			GroupTopics: cfg.Topics,
+			StartOffset: kafka.FirstOffset,
+		}),
+		publisher: publisher,
+		handlers:  make(map[string]Handler),
+		topics:    make(map[string]Handler),
+	}
+}
+
+// Handle регистрирует обработчик для типа события; вызывается до Run
+func (c *Consumer) Handle(eventType string, handler Handler) {
+	c.handlers[eventType] = handler
+}
+
+// HandleTopic регистрирует обработчик всех сообщений топика, в том числе не в формате CloudEvents
+// (тип пустой) и с незнакомым типом. Обработчик топика важнее обработчика типа; вызывается до Run
+func (c *Consumer) HandleTopic(topic string, handler Handler) {
+	c.topics[topic] = handler
+}
+
+// Run читает сообщения, пока не будет отменен ctx, затем дожидается воркеров и закрывает reader.
+// Сообщения, обработка которых не завершилась, не фиксируются и будут получены повторно
+func (c *Consumer) Run(ctx context.Context) error {
+	queues := make([]chan kafka.Message, c.cfg.Workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan kafka.Message)
+		wg.Add(1)
+		go func(queue <-chan kafka.Message) {
+			defer wg.Done()
+			for km := range queue {
+				c.process(ctx, km)
+			}
+		}(queues[i])
+	}
+
+	c.fetch(ctx, queues)
+
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+	if err := c.reader.Close(); err != nil {
+		slog.Error("Error closing consumer", "error", err)
+		return err
+	}
+	slog.Info("Consumer stopped")
+	return nil
+}
+
+// fetch распределяет сообщения по воркерам: партиция всегда попадает к одному воркеру.
+// Ошибка чтения (недоступный брокер, перебалансировка группы) не останавливает потребителя:
+// чтение повторяется с экспоненциальной задержкой, пока не будет отменен ctx
+func (c *Consumer) fetch(ctx context.Context, queues []chan kafka.Message) {
+	delay := c.cfg.MinBackoff
+	for {
+		km, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.ErrorContext(ctx, "Failed to fetch message", "retry_in", delay, "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > c.cfg.MaxBackoff {
+				delay = c.cfg.MaxBackoff
+			}
+			continue
+		}
+		delay = c.cfg.MinBackoff
+
+		select {
+		case queues[worker(km, len(queues))] <- km:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func worker(km kafka.Message, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(km.Topic))
+	h.Write([]byte(strconv.Itoa(km.Partition)))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// process обрабатывает сообщение с повторными попытками и фиксирует смещение
+func (c *Consumer) process(ctx context.Context, km kafka.Message) {
+	if ctx.Err() != nil {
+		return // остановка: сообщение будет получено повторно
+	}
+
+	message := newMessage(km)
+	// Обработка продолжает трассу, в которой событие было опубликовано
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, message.Headers), km.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationName(km.Topic),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(km.Partition)),
+			semconv.MessagingKafkaMessageOffset(int(km.Offset)),
+			semconv.CloudeventsEventType(message.Type),
+			semconv.CloudeventsEventID(message.ID),
+		))
+	defer span.End()
+	// Логи обработчика и сервисов содержат координаты сообщения
+	ctx = logging.With(ctx, "topic", km.Topic, "partition", km.Partition, "offset", km.Offset, "event_type", message.Type, "event_id", message.ID)
+
+	handler, ok := c.topics[km.Topic]
+	if !ok {
+		handler, ok = c.handlers[message.Type]
+	}
+	if !ok {
+		slog.WarnContext(ctx, "No handler for message, skipping")
+		c.commit(ctx, km)
+		return
+	}
+
+	attempts, err := c.handle(ctx, handler, message)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if ctx.Err() != nil {
+			return
+		}
+		slog.ErrorContext(ctx, "Failed to handle message", "attempts", attempts, "error", err)
+		if !c.deadLetter(ctx, km, attempts, err) {
+			return
+		}
+	}
+	c.commit(ctx, km)
+}
+
+// handle вызывает обработчик до MaxAttempts раз и возвращает число попыток и последнюю ошибку
+func (c *Consumer) handle(ctx context.Context, handler Handler, message Message) (int, error) {
+	delay := c.cfg.MinBackoff
+	for attempt := 1; ; attempt++ {
+		err := handler(ctx, message)
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) || attempt >= c.cfg.MaxAttempts {
+			return attempt, err
+		}
+
+		slog.WarnContext(ctx, "Retrying message", "attempt", attempt, "retry_in", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > c.cfg.MaxBackoff {
+			delay = c.cfg.MaxBackoff
+		}
+	}
+}
+
+// deadLetter отправляет сообщение в dead-letter топик, повторяя отправку до успеха или остановки.
+// Возвращает true, если смещение можно фиксировать
+func (c *Consumer) deadLetter(ctx context.Context, km kafka.Message, attempts int, cause error) bool {
+	if c.cfg.DeadLetterTopic == "" {
+		return true
+	}
+
+	headers := map[string]string{
+		"dlq-original-topic":     km.Topic,
+		"dlq-original-partition": strconv.Itoa(km.Partition),
+		"dlq-original-offset":    strconv.FormatInt(km.Offset, 10),
+		"dlq-attempts":           strconv.Itoa(attempts),
+		"dlq-error":              cause.Error(),
+	}
+	for _, h := range km.Headers {
+		if _, ok := headers[h.Key]; !ok {
+			headers[h.Key] = string(h.Value)
+		}
+	}
+	message := events.Message{Topic: c.cfg.DeadLetterTopic, Key: string(km.Key), Value: km.Value, Headers: headers}
+
+	delay := c.cfg.MinBackoff
+	for {
+		err := c.publisher.Publish(ctx, message)
+		if err == nil {
+			return true
+		}
+		slog.ErrorContext(ctx, "Failed to send message to dead-letter topic", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > c.cfg.MaxBackoff {
+			delay = c.cfg.MaxBackoff
+		}
+	}
+}
+
+// commit фиксирует смещение; выполняется и после отмены ctx, чтобы не обрабатывать сообщение повторно
+func (c *Consumer) commit(ctx context.Context, km kafka.Message) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), commitTimeout)
+	defer cancel()
+	if err := c.reader.CommitMessages(ctx, km); err != nil {
+		slog.ErrorContext(ctx, "Failed to commit message", "topic", km.Topic, "partition", km.Partition, "offset", km.Offset, "error", err)
+	}
+}
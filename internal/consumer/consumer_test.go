@@ -0,0 +1,141 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"pharmacy-api/internal/events"
+
+	"github.com/segmentio/kafka-go"
+)
+
+var errTemporary = errors.New("database unavailable")
+
+func TestHandle(t *testing.T) {
+	tests := []struct {
+		name         string
+		results      []error // ошибки попыток по порядку; после последней обработчик успешен
+		maxAttempts  int
+		wantAttempts int
+		wantErr      error
+	}{
+		{name: "success", maxAttempts: 3, wantAttempts: 1},
+		{name: "retried until success", results: []error{errTemporary, errTemporary}, maxAttempts: 3, wantAttempts: 3},
+		{name: "attempts exhausted", results: []error{errTemporary, errTemporary, errTemporary}, maxAttempts: 3, wantAttempts: 3, wantErr: errTemporary},
+		{name: "permanent error not retried", results: []error{Permanent(errTemporary)}, maxAttempts: 3, wantAttempts: 1, wantErr: errTemporary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Consumer{cfg: Config{MaxAttempts: tt.maxAttempts, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}}
+			calls := 0
+			handler := func(ctx context.Context, message Message) error {
+				calls++
+				if calls <= len(tt.results) {
+					return tt.results[calls-1]
+				}
+				return nil
+			}
+
+			attempts, err := c.handle(context.Background(), handler, Message{})
+			if attempts != tt.wantAttempts || calls != tt.wantAttempts {
+				t.Errorf("attempts = %d, calls = %d, want %d", attempts, calls, tt.wantAttempts)
+			}
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleStopsOnCancel(t *testing.T) {
+	c := &Consumer{cfg: Config{MaxAttempts: 5, MinBackoff: time.Hour, MaxBackoff: time.Hour}}
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := func(ctx context.Context, message Message) error {
+		cancel()
+		return errTemporary
+	}
+
+	attempts, err := c.handle(ctx, handler, Message{})
+	if attempts != 1 || !errors.Is(err, context.Canceled) {
+		t.Errorf("attempts = %d, error = %v, want 1 and context.Canceled", attempts, err)
+	}
+}
+
+// flakyPublisher отказывает первые failures раз
+type flakyPublisher struct {
+	*events.MemoryPublisher
+	failures int
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, messages ...events.Message) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	return p.MemoryPublisher.Publish(ctx, messages...)
+}
+
+func TestDeadLetter(t *testing.T) {
+	km := kafka.Message{
+		Topic:     "deliveries",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("delivery-1"),
+		Value:     []byte(`{"broken"`),
+		Headers:   []kafka.Header{{Key: "traceparent", Value: []byte("00-trace")}, {Key: "dlq-attempts", Value: []byte("spoofed")}},
+	}
+	publisher := &flakyPublisher{MemoryPublisher: events.NewMemoryPublisher(), failures: 1}
+	c := &Consumer{
+		cfg:       Config{DeadLetterTopic: "deliveries.dlq", MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		publisher: publisher,
+	}
+
+	if !c.deadLetter(context.Background(), km, 5, errTemporary) {
+		t.Fatal("deadLetter = false, want true")
+	}
+	want := []events.Message{{
+		Topic: "deliveries.dlq",
+		Key:   "delivery-1",
+		Value: km.Value,
+		Headers: map[string]string{
+			"dlq-original-topic":     "deliveries",
+			"dlq-original-partition": "2",
+			"dlq-original-offset":    "42",
+			"dlq-attempts":           "5",
+			"dlq-error":              "database unavailable",
+			"traceparent":            "00-trace",
+		},
+	}}
+	if got := publisher.Messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("dead-letter messages = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeadLetterWithoutTopic(t *testing.T) {
+	publisher := events.NewMemoryPublisher()
+	c := &Consumer{publisher: publisher}
+
+	if !c.deadLetter(context.Background(), kafka.Message{Topic: "deliveries"}, 1, errTemporary) {
+		t.Error("deadLetter = false, want true")
+	}
+	if got := publisher.Messages(); len(got) != 0 {
+		t.Errorf("published %d messages, want none", len(got))
+	}
+}
+
+func TestDeadLetterStopsOnCancel(t *testing.T) {
+	c := &Consumer{
+		cfg:       Config{DeadLetterTopic: "deliveries.dlq", MinBackoff: time.Hour, MaxBackoff: time.Hour},
+		publisher: &flakyPublisher{MemoryPublisher: events.NewMemoryPublisher(), failures: 1},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Смещение не фиксируется: сообщение будет получено повторно после перезапуска
+	if c.deadLetter(ctx, kafka.Message{Topic: "deliveries"}, 1, errTemporary) {
+		t.Error("deadLetter = true, want false")
+	}
+}
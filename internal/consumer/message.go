@@ -0,0 +1,69 @@
+package consumer
+
+import (
+	"encoding/json"
+	"strings"
+
+	"pharmacy-api/internal/events"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Message - полученное событие.
+// Поддерживаются оба режима CloudEvents для Kafka: структурированный (конверт в теле сообщения)
+// и бинарный (атрибуты в заголовках ce_*, данные - тело сообщения)
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       string
+	Headers   map[string]string
+
+	ID      string // атрибут id
+	Type    string // атрибут type; пустой, если сообщение не в формате CloudEvents
+	Subject string // атрибут subject
+	Data    []byte // данные события
+}
+
+// newMessage разбирает сообщение Kafka
+func newMessage(km kafka.Message) Message {
+	m := Message{
+		Topic:     km.Topic,
+		Partition: km.Partition,
+		Offset:    km.Offset,
+		Key:       string(km.Key),
+		Headers:   make(map[string]string, len(km.Headers)),
+		Data:      km.Value,
+	}
+	for _, h := range km.Headers {
+		m.Headers[h.Key] = string(h.Value)
+	}
+
+	// Бинарный режим
+	if eventType := m.Headers["ce_type"]; eventType != "" {
+		m.Type = eventType
+		m.ID = m.Headers["ce_id"]
+		m.Subject = m.Headers["ce_subject"]
+		return m
+	}
+
+	// Структурированный режим
+	if strings.HasPrefix(m.Headers["content-type"], events.ContentType) || looksLikeCloudEvent(km.Value) {
+		var envelope events.Envelope
+		if err := json.Unmarshal(km.Value, &envelope); err == nil && envelope.Type != "" {
+			m.Type = envelope.Type
+			m.ID = envelope.ID
+			m.Subject = envelope.Subject
+			m.Data = envelope.Data
+		}
+	}
+	return m
+}
+
+// looksLikeCloudEvent проверяет наличие атрибута specversion, если заголовок content-type не передан
+func looksLikeCloudEvent(value []byte) bool {
+	var probe struct {
+		SpecVersion string `json:"specversion"`
+	}
+	return json.Unmarshal(value, &probe) == nil && probe.SpecVersion != ""
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// SpecVersion - версия спецификации CloudEvents
+	SpecVersion = "1.0"
+	// Source - источник всех событий приложения (атрибут source)
+	Source = "/pharmacy-api"
+	// ContentType - тип сообщения в структурированном режиме CloudEvents
+	ContentType = "application/cloudevents+json"
+)
+
+// Envelope - событие в формате CloudEvents 1.0 (структурированный режим, JSON)
+type Envelope struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            time.Time       `json:"time"`
+	Subject         string          `json:"subject,omitempty"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema"`
+	Data            json.RawMessage `json:"data"`
+
+	// Атрибуты-расширения
+	CorrelationID string `json:"correlationid,omitempty"` // ID цепочки запросов, породившей событие
+	ActorID       uint   `json:"actorid,omitempty"`       // ID пользователя, выполнившего действие
+}
+
+// NewEnvelope оборачивает данные события в CloudEvents.
+// eventType должен быть зарегистрирован в Types; subject - ID сущности, к которой относится событие
+func NewEnvelope(eventType, subject string, data interface{}) (Envelope, error) {
+	schema, ok := Types[eventType]
+	if !ok {
+		return Envelope{}, fmt.Errorf("unknown event type %q", eventType)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to marshal %s data: %w", eventType, err)
+	}
+
+	return Envelope{
+		SpecVersion:     SpecVersion,
+		ID:              uuid.NewString(),
+		Source:          Source,
+		Type:            eventType,
+		Time:            time.Now().UTC(),
+		Subject:         subject,
+		DataContentType: "application/json",
+		DataSchema:      SchemaURI(schema),
+		Data:            raw,
+	}, nil
+}
+
+// Message возвращает событие как сообщение для публикации
+func (e Envelope) Message(topic, key string) (Message, error) {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Topic:   topic,
+		Key:     key,
+		Value:   value,
+		Headers: map[string]string{"content-type": ContentType},
+	}, nil
+}
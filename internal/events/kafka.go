@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"pharmacy-api/internal/tracing"
+)
+
+// KafkaPublisher публикует сообщения в Kafka синхронно: Publish ждет подтверждения всех реплик
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher создает новый экземпляр KafkaPublisher.
+// Топик задается в каждом сообщении, поэтому один писатель обслуживает все топики
+func NewKafkaPublisher(brokers []string) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{}, // партиция по ключу сообщения
+			RequiredAcks:           kafka.RequireAll,
+			BatchTimeout:           10 * time.Millisecond, // синхронная запись не ждет заполнения пачки
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+// Publish отправляет сообщения и ждет подтверждения брокера.
+// Запись оформляется спаном, контекст которого передается получателям в заголовках (W3C traceparent)
+func (p *KafkaPublisher) Publish(ctx context.Context, messages ...Message) (err error) {
+	name := "publish"
+	attributes := []attribute.KeyValue{
+		semconv.MessagingSystemKafka,
+		semconv.MessagingOperationTypePublish,
+		semconv.MessagingBatchMessageCount(len(messages)),
+	}
+	if topic := commonTopic(messages); topic != "" {
+		name = topic + " publish"
+		attributes = append(attributes, semconv.MessagingDestinationName(topic))
+	}
+	ctx, span := tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(attributes...))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	kafkaMessages := make([]kafka.Message, 0, len(messages))
+	for _, m := range messages {
+		// Заголовки копируются, чтобы не менять сообщение вызывающего
+		headers := make(map[string]string, len(m.Headers)+2)
+		for k, v := range m.Headers {
+			headers[k] = v
+		}
+		tracing.Inject(ctx, headers)
+
+		km := kafka.Message{
+			Topic: m.Topic,
+			Value: m.Value,
+		}
+		if m.Key != "" {
+			km.Key = []byte(m.Key)
+		}
+		for k, v := range headers {
+			km.Headers = append(km.Headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+		kafkaMessages = append(kafkaMessages, km)
+	}
+
+	if err := p.writer.WriteMessages(ctx, kafkaMessages...); err != nil {
+		return fmt.Errorf("failed to publish messages: %w", err)
+	}
+	return nil
+}
+
+// commonTopic возвращает топик, если все сообщения в одном топике, иначе пустую строку
+func commonTopic(messages []Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	topic := messages[0].Topic
+	for _, m := range messages[1:] {
+		if m.Topic != topic {
+			return ""
+		}
+	}
+	return topic
+}
+
+// Close дожидается отправки буферизованных сообщений и закрывает соединения
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// Ping запрашивает у брокеров метаданные кластера: ошибка - ни один брокер не ответил
+func (p *KafkaPublisher) Ping(ctx context.Context) error {
+	client := &kafka.Client{Addr: p.writer.Addr}
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{}})
+	if err != nil {
+		return fmt.Errorf("failed to fetch cluster metadata: %w", err)
+	}
+	if len(metadata.Brokers) == 0 {
+		return fmt.Errorf("cluster metadata lists no brokers")
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Package events - публикация событий приложения в брокер сообщений.
+// Сервисы зависят только от интерфейса Publisher, поэтому для тестов и локального
+// запуска без Kafka достаточно MemoryPublisher или NoopPublisher
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Message - сообщение для публикации
+type Message struct {
+	Topic   string
+	Key     string // сообщения с одинаковым ключом попадают в одну партицию
+	Value   []byte
+	Headers map[string]string
+}
+
+// Publisher публикует сообщения. Publish возвращает nil только после того,
+// как брокер подтвердил запись
+type Publisher interface {
+	Publish(ctx context.Context, messages ...Message) error
+	Close() error
+}
+
+// NoopPublisher отбрасывает все сообщения (запуск без брокера)
+type NoopPublisher struct{}
+
+// Publish ничего не делает
+func (NoopPublisher) Publish(ctx context.Context, messages ...Message) error {
+	return nil
+}
+
+// Close ничего не делает
+func (NoopPublisher) Close() error {
+	return nil
+}
+
+// MemoryPublisher сохраняет сообщения в памяти (тесты, локальная отладка)
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryPublisher создает новый экземпляр MemoryPublisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish сохраняет сообщения
+func (p *MemoryPublisher) Publish(ctx context.Context, messages ...Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+// Messages возвращает копию опубликованных сообщений
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.messages...)
+}
+
+// Close ничего не делает
+func (p *MemoryPublisher) Close() error {
+	return nil
+}
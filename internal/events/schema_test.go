@@ -0,0 +1,344 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gorm.io/gorm"
+
+	"pharmacy-api/internal/events"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+	"pharmacy-api/internal/services"
+)
+
+// Схемы проверяются на событиях, которые отправляют настоящие производители: сервис лекарств
+// пишет их в outbox, AuthService публикует сразу. Репозитории заменены хранилищами в памяти
+
+const (
+	medicineTopic = "pharmacy.medicines"
+	loginTopic    = "pharmacy.logins"
+	signupTopic   = "pharmacy.registrations"
+)
+
+var sampleTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// produceEvents проводит лекарство и пользователя через все изменения, о которых пишутся события
+func produceEvents(t *testing.T) []events.Message {
+	t.Helper()
+	ctx := services.WithCorrelationID(services.WithActor(context.Background(), 1), "3f6c1d2e-8a4b-4c7e-9f10-2b3c4d5e6f70")
+
+	store := &medicineStore{items: make(map[int]models.Medicine)}
+	outbox := &outboxCapture{}
+	medicines := services.NewMedicineService(store, transactor{tx{store, outbox}}, medicineTopic)
+
+	aspirin, err := medicines.CreateMedicine(ctx, models.Medicine{
+		Name: "Aspirin", Description: "Pain relief", Barcode: "4600000000001", Price: 99.5, Quantity: 10, ReorderPoint: 5,
+	})
+	must(t, err)
+	aspirin.Price, aspirin.Quantity = 89.9, 20
+	aspirin, err = medicines.UpdateMedicine(ctx, int(aspirin.ID), aspirin, aspirin.Version, services.UpdateOptions{})
+	must(t, err)
+	must(t, medicines.DeleteMedicine(ctx, int(aspirin.ID), aspirin.Version))
+	aspirin, err = medicines.RestoreMedicine(ctx, int(aspirin.ID))
+	must(t, err)
+	must(t, medicines.DeleteMedicine(ctx, int(aspirin.ID), aspirin.Version))
+	must(t, medicines.PurgeMedicine(ctx, int(aspirin.ID)))
+
+	// Удаление из корзины по сроку хранения
+	ibuprofen, err := medicines.CreateMedicine(ctx, models.Medicine{Name: "Ibuprofen", Price: 150, Quantity: 3})
+	must(t, err)
+	must(t, medicines.DeleteMedicine(ctx, int(ibuprofen.ID), ibuprofen.Version))
+	_, err = medicines.PurgeExpiredTrash(ctx, 0)
+	must(t, err)
+
+	publisher := events.NewMemoryPublisher()
+	auth := services.NewAuthService(&userStore{}, nil, publisher, services.AuthConfig{
+		JWTSecret:         "secret",
+		TokenTTL:          time.Hour,
+		LoginTopic:        loginTopic,
+		RegistrationTopic: signupTopic,
+	})
+	must(t, auth.Register(ctx, "alice", "correct-horse"))
+	_, err = auth.Login(ctx, "alice", "correct-horse")
+	must(t, err)
+	if _, err := auth.Login(ctx, "alice", "wrong-password"); err == nil {
+		t.Fatal("login with a wrong password succeeded")
+	}
+
+	messages := outbox.messages()
+	if len(messages) != 9 {
+		t.Fatalf("medicine service wrote %d outbox messages, want 9", len(messages))
+	}
+	return append(messages, publisher.Messages()...)
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// medicineStore - каталог в памяти; реализует методы, которые вызывает сервис лекарств
+type medicineStore struct {
+	repositories.MedicineRepository
+	items  map[int]models.Medicine
+	nextID uint
+}
+
+func (s *medicineStore) Create(ctx context.Context, medicine models.Medicine) (models.Medicine, error) {
+	s.nextID++
+	medicine.ID = s.nextID
+	medicine.Version = 1
+	medicine.CreatedAt, medicine.UpdatedAt = sampleTime, sampleTime
+	s.items[int(medicine.ID)] = medicine
+	return medicine, nil
+}
+
+func (s *medicineStore) GetByID(ctx context.Context, id int) (models.Medicine, error) {
+	medicine, ok := s.items[id]
+	if !ok || medicine.DeletedAt.Valid {
+		return models.Medicine{}, repositories.ErrNotFound
+	}
+	return medicine, nil
+}
+
+func (s *medicineStore) Update(ctx context.Context, id int, medicine models.Medicine, version uint) (models.Medicine, error) {
+	current, err := s.GetByID(ctx, id)
+	if err != nil {
+		return models.Medicine{}, err
+	}
+	medicine.Model = current.Model
+	medicine.UpdatedAt = sampleTime.Add(time.Hour)
+	medicine.Version = version + 1
+	s.items[id] = medicine
+	return medicine, nil
+}
+
+func (s *medicineStore) Delete(ctx context.Context, id int, version uint) error {
+	medicine, err := s.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	// Как и репозиторий, удаление проверяет версию, но не увеличивает ее
+	if medicine.Version != version {
+		return repositories.ErrVersionConflict
+	}
+	medicine.DeletedAt = gorm.DeletedAt{Time: sampleTime.Add(2 * time.Hour), Valid: true}
+	s.items[id] = medicine
+	return nil
+}
+
+func (s *medicineStore) GetDeletedByID(ctx context.Context, id int) (models.Medicine, error) {
+	medicine, ok := s.items[id]
+	if !ok || !medicine.DeletedAt.Valid {
+		return models.Medicine{}, repositories.ErrNotFound
+	}
+	return medicine, nil
+}
+
+func (s *medicineStore) Restore(ctx context.Context, id int) (models.Medicine, error) {
+	medicine, err := s.GetDeletedByID(ctx, id)
+	if err != nil {
+		return models.Medicine{}, err
+	}
+	medicine.DeletedAt = gorm.DeletedAt{}
+	medicine.Version++
+	s.items[id] = medicine
+	return medicine, nil
+}
+
+func (s *medicineStore) Purge(ctx context.Context, id int) error {
+	if _, err := s.GetDeletedByID(ctx, id); err != nil {
+		return err
+	}
+	delete(s.items, id)
+	return nil
+}
+
+func (s *medicineStore) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) ([]models.Medicine, error) {
+	var purged []models.Medicine
+	for id, medicine := range s.items {
+		if medicine.DeletedAt.Valid && medicine.DeletedAt.Time.Before(cutoff) {
+			purged = append(purged, medicine)
+			delete(s.items, id)
+		}
+	}
+	return purged, nil
+}
+
+// outboxCapture сохраняет сообщения, которые сервис пишет в outbox
+type outboxCapture struct {
+	repositories.OutboxRepository
+	added []models.OutboxMessage
+}
+
+func (o *outboxCapture) Add(ctx context.Context, message *models.OutboxMessage) error {
+	o.added = append(o.added, *message)
+	return nil
+}
+
+// messages возвращает сообщения outbox в том виде, в каком их опубликует outbox.Relay
+func (o *outboxCapture) messages() []events.Message {
+	messages := make([]events.Message, len(o.added))
+	for i, m := range o.added {
+		messages[i] = events.Message{Topic: m.Topic, Key: m.Key, Value: []byte(m.Payload)}
+	}
+	return messages
+}
+
+type tx struct {
+	medicines *medicineStore
+	outbox    *outboxCapture
+}
+
+func (t tx) Medicines() repositories.MedicineRepository  { return t.medicines }
+func (t tx) Outbox() repositories.OutboxRepository       { return t.outbox }
+func (t tx) Deliveries() repositories.DeliveryRepository { return nil }
+
+type transactor struct {
+	tx tx
+}
+
+func (t transactor) Transaction(ctx context.Context, fn func(repositories.Tx) error) error {
+	return fn(t.tx)
+}
+
+// userStore - пользователи в памяти
+type userStore struct {
+	repositories.UserRepository
+	users []*models.User
+}
+
+func (s *userStore) Create(ctx context.Context, user *models.User) error {
+	user.ID = uint(len(s.users) + 1)
+	s.users = append(s.users, user)
+	return nil
+}
+
+func (s *userStore) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func compileSchemas(t *testing.T) map[string]*jsonschema.Schema {
+	t.Helper()
+
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat = true
+	files, err := fs.Glob(events.Schemas, "schemas/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		f, err := events.Schemas.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = compiler.AddResource(events.SchemaURI(strings.TrimPrefix(file, "schemas/")), f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+
+	schemas := make(map[string]*jsonschema.Schema, len(files))
+	for _, file := range files {
+		name := strings.TrimPrefix(file, "schemas/")
+		schema, err := compiler.Compile(events.SchemaURI(name))
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		schemas[name] = schema
+	}
+	return schemas
+}
+
+// decode разбирает сообщение как конверт CloudEvents
+func decode(t *testing.T, message events.Message) map[string]interface{} {
+	t.Helper()
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(message.Value, &decoded); err != nil {
+		t.Fatalf("%s: %v", message.Topic, err)
+	}
+	return decoded
+}
+
+func TestEveryTypeIsProduced(t *testing.T) {
+	produced := make(map[string]bool)
+	for _, message := range produceEvents(t) {
+		if eventType, ok := decode(t, message)["type"].(string); ok {
+			produced[eventType] = true
+		}
+	}
+	for eventType := range events.Types {
+		if !produced[eventType] {
+			t.Errorf("%s: no producer emitted this event", eventType)
+		}
+	}
+}
+
+func TestEventsMatchSchemas(t *testing.T) {
+	schemas := compileSchemas(t)
+	envelopeSchema := schemas[events.EnvelopeSchema]
+
+	for _, message := range produceEvents(t) {
+		decoded := decode(t, message)
+		eventType, _ := decoded["type"].(string)
+		schemaName, ok := events.Types[eventType]
+		if !ok {
+			t.Errorf("%s: unknown event type %q", message.Topic, eventType)
+			continue
+		}
+		dataSchema, ok := schemas[schemaName]
+		if !ok {
+			t.Errorf("%s: schema %q not found", eventType, schemaName)
+			continue
+		}
+
+		if err := envelopeSchema.Validate(decoded); err != nil {
+			t.Errorf("%s: envelope does not match %s: %v", eventType, events.EnvelopeSchema, err)
+		}
+		if err := dataSchema.Validate(decoded["data"]); err != nil {
+			t.Errorf("%s: data does not match %s: %v", eventType, schemaName, err)
+		}
+		if decoded["dataschema"] != events.SchemaURI(schemaName) {
+			t.Errorf("%s: dataschema = %v, want %s", eventType, decoded["dataschema"], events.SchemaURI(schemaName))
+		}
+		if decoded["subject"] != message.Key {
+			t.Errorf("%s: subject = %v, want message key %q", eventType, decoded["subject"], message.Key)
+		}
+	}
+}
+
+func TestSchemaIDsMatchFileNames(t *testing.T) {
+	files, err := fs.Glob(events.Schemas, "schemas/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := events.Schemas.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var schema struct {
+			ID string `json:"$id"`
+		}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if want := events.SchemaURI(strings.TrimPrefix(file, "schemas/")); schema.ID != want {
+			t.Errorf("%s: $id = %q, want %q", file, schema.ID, want)
+		}
+	}
+}
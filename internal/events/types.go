@@ -0,0 +1,79 @@
+package events
+
+import (
+	"embed"
+	"time"
+)
+
+// Типы событий. Версия входит в тип: несовместимое изменение данных требует нового типа
+// (например, .v2) и новой схемы, а добавление необязательных полей допустимо в текущей версии
+const (
+	MedicineCreated  = "pharmacy.medicine.created.v1"
+	MedicineUpdated  = "pharmacy.medicine.updated.v1"
+	MedicineDeleted  = "pharmacy.medicine.deleted.v1"
+	MedicineRestored = "pharmacy.medicine.restored.v1"
+	MedicinePurged   = "pharmacy.medicine.purged.v1"
+	UserRegistered   = "pharmacy.user.registered.v1"
+	UserLogin        = "pharmacy.user.login.v1"
+)
+
+// Types - схема данных (файл в schemas/) для каждого типа события
+var Types = map[string]string{
+	MedicineCreated:  "medicine.v1.json",
+	MedicineUpdated:  "medicine.v1.json",
+	MedicineDeleted:  "medicine.v1.json",
+	MedicineRestored: "medicine.v1.json",
+	MedicinePurged:   "medicine.v1.json",
+	UserRegistered:   "user-registered.v1.json",
+	UserLogin:        "user-login.v1.json",
+}
+
+// EnvelopeSchema - схема конверта CloudEvents в schemas/
+const EnvelopeSchema = "cloudevent.json"
+
+// Schemas - JSON Schema конверта и данных событий
+//
+//go:embed schemas/*.json
+var Schemas embed.FS
+
+// SchemaURI возвращает идентификатор схемы ($id), который указывается в атрибуте dataschema
+func SchemaURI(file string) string {
+	return "urn:pharmacy-api:schemas:" + file
+}
+
+// MedicineEventV1 - данные событий pharmacy.medicine.*.v1.
+// Before заполняется для updated, deleted и purged, After - для created, updated и restored
+type MedicineEventV1 struct {
+	MedicineID    uint        `json:"medicine_id"`
+	Before        *MedicineV1 `json:"before,omitempty"`
+	After         *MedicineV1 `json:"after,omitempty"`
+	ChangedFields []string    `json:"changed_fields,omitempty"` // только для updated
+}
+
+// MedicineV1 - состояние лекарства в событии
+type MedicineV1 struct {
+	ID           uint       `json:"id"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Barcode      string     `json:"barcode"`
+	Price        float64    `json:"price"`
+	Quantity     int        `json:"quantity"`
+	ReorderPoint int        `json:"reorder_point"`
+	Version      uint       `json:"version"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at"`
+}
+
+// UserRegisteredV1 - данные события pharmacy.user.registered.v1
+type UserRegisteredV1 struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// UserLoginV1 - данные события pharmacy.user.login.v1 (успешная и неуспешная попытка входа)
+type UserLoginV1 struct {
+	Username    string `json:"username"`
+	Success     bool   `json:"success"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pharmacy-api/internal/consumer"
+	"pharmacy-api/internal/middleware"
+	"pharmacy-api/internal/services"
+)
+
+// DeliveryHandler - обработчики поставок: события складской системы и ручной разбор несопоставленных строк
+type DeliveryHandler struct {
+	deliveryService services.DeliveryService
+}
+
+// NewDeliveryHandler создает новый экземпляр DeliveryHandler
+func NewDeliveryHandler(deliveryService services.DeliveryService) *DeliveryHandler {
+	return &DeliveryHandler{
+		deliveryService: deliveryService,
+	}
+}
+
+// HandleDeliveryEvent - обработчик накладных для consumer.Consumer: события warehouse.delivery.received.v1
+// или накладной простым JSON из топика поставок.
+// Некорректные накладные не исправятся повторной попыткой и сразу уходят в dead-letter топик
+func (h *DeliveryHandler) HandleDeliveryEvent(ctx context.Context, message consumer.Message) error {
+	var delivery services.Delivery
+	if err := json.Unmarshal(message.Data, &delivery); err != nil {
+		return consumer.Permanent(fmt.Errorf("invalid delivery: %w", err))
+	}
+
+	err := h.deliveryService.ProcessDelivery(ctx, delivery)
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		return consumer.Permanent(fmt.Errorf("invalid delivery %q: %w", delivery.DeliveryID, err))
+	}
+	return err
+}
+
+// GetUnmatchedItems - список строк поставок, для которых не нашлось лекарства
+func (h *DeliveryHandler) GetUnmatchedItems(c *gin.Context) {
+	items, err := h.deliveryService.GetUnmatchedItems(c.Request.Context())
+	if err != nil {
+		c.Error(fmt.Errorf("failed to get unmatched delivery items: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// ResolveUnmatchedRequest - тело запроса на сопоставление строки поставки
+type ResolveUnmatchedRequest struct {
+	MedicineID int `json:"medicine_id" binding:"required,gt=0"`
+}
+
+// ResolveUnmatchedItem - сопоставляет строку поставки с лекарством и учитывает приход
+func (h *DeliveryHandler) ResolveUnmatchedItem(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid ID"))
+		return
+	}
+	var req ResolveUnmatchedRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	receipt, err := h.deliveryService.ResolveUnmatchedItem(c.Request.Context(), id, req.MedicineID)
+	if err != nil {
+		c.Error(fmt.Errorf("failed to resolve delivery item: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, receipt)
+}
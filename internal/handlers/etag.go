@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"pharmacy-api/internal/middleware"
+)
+
+// setETag отдает версию лекарства в заголовке ETag
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", `"`+strconv.FormatUint(uint64(version), 10)+`"`)
+}
+
+// ifMatchVersion читает ожидаемую версию из заголовка If-Match.
+// Без заголовка регистрирует ошибку 428, при неверном формате - 400, и возвращает false.
+// "*" и слабые ETag не принимаются: изменение всегда должно опираться на конкретную версию
+func ifMatchVersion(c *gin.Context) (uint, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.Error(middleware.NewHTTPError(http.StatusPreconditionRequired, "If-Match header with the current ETag is required"))
+		return 0, false
+	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "If-Match must be a single strong ETag"))
+		return 0, false
+	}
+	version, err := strconv.ParseUint(tag, 10, 64)
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "If-Match must be a single strong ETag"))
+		return 0, false
+	}
+	return uint(version), true
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck проверяет доступность одной зависимости
+type HealthCheck func(ctx context.Context) error
+
+// CheckResult - результат проверки зависимости
+type CheckResult struct {
+	Status   string `json:"status"` // up или down
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// HealthResponse - ответ /readyz
+type HealthResponse struct {
+	Status string                 `json:"status"` // ready, not_ready или draining
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// HealthHandler отвечает на проверки живости и готовности (балансировщик, оркестратор)
+type HealthHandler struct {
+	timeout  time.Duration
+	names    []string
+	checks   map[string]HealthCheck
+	draining atomic.Bool
+}
+
+// NewHealthHandler создает новый экземпляр HealthHandler; timeout ограничивает каждую проверку
+func NewHealthHandler(timeout time.Duration) *HealthHandler {
+	return &HealthHandler{timeout: timeout, checks: make(map[string]HealthCheck)}
+}
+
+// AddCheck добавляет проверку зависимости для /readyz. Вызывается до запуска сервера
+func (h *HealthHandler) AddCheck(name string, check HealthCheck) {
+	h.names = append(h.names, name)
+	sort.Strings(h.names)
+	h.checks[name] = check
+}
+
+// SetDraining переводит экземпляр в состояние "не готов": балансировщик перестает
+// направлять новые запросы, пока текущие завершаются
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Liveness - процесс жив и обслуживает HTTP; зависимости не проверяются,
+// чтобы недоступная база не приводила к перезапуску всех экземпляров
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness - экземпляр готов принимать запросы: все зависимости доступны и не идет остановка.
+// Проверки выполняются параллельно; 503, если хотя бы одна не прошла
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	response := HealthResponse{Status: "ready", Checks: make(map[string]CheckResult, len(h.names))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range h.names {
+		name, check := name, h.checks[name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := h.run(c.Request.Context(), check)
+			mu.Lock()
+			response.Checks[name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	for _, result := range response.Checks {
+		if result.Status != "up" {
+			response.Status = "not_ready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	if h.draining.Load() {
+		response.Status = "draining"
+		status = http.StatusServiceUnavailable
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, response)
+}
+
+// run выполняет проверку не дольше timeout
+func (h *HealthHandler) run(ctx context.Context, check HealthCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{Status: "up", Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status = "down"
+		result.Error = err.Error()
+	}
+	return result
+}
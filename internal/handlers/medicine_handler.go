@@ -2,15 +2,20 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"pharmacy-api/internal/catalog"
+	"pharmacy-api/internal/middleware"
 	"pharmacy-api/internal/models"      // Добавьте импорт вашей модели Medicine
 	"pharmacy-api/internal/services" // Импорт сервиса
 )
@@ -18,39 +23,32 @@ import (
 // MedicineHandler - структура для обработчиков medicine
 type MedicineHandler struct {
 	medicineService services.MedicineService // Change to services.MedicineService (not pointer)
-	kafkaProducer   *kafka.Producer
-	kafkaTopic      string
 }
 
 // NewMedicineHandler создает новый экземпляр MedicineHandler
-func NewMedicineHandler(medicineService services.MedicineService, kafkaProducer *kafka.Producer, kafkaTopic string) *MedicineHandler {
+func NewMedicineHandler(medicineService services.MedicineService) *MedicineHandler {
 	return &MedicineHandler{
 		medicineService: medicineService,
-		kafkaProducer:   kafkaProducer,
-		kafkaTopic:      kafkaTopic,
 	}
 }
 
 // CreateMedicine - создает новое лекарство
 func (h *MedicineHandler) CreateMedicine(c *gin.Context) {
-	// 1. Получаем данные запроса
-	var medicine models.Medicine
-	if err := c.ShouldBindJSON(&medicine); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// 1. Получаем и проверяем данные запроса
+	var req MedicineRequest
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// 2. Создаем лекарство (с помощью medicineService)
-	createdMedicine, err := h.medicineService.CreateMedicine(medicine)
+	createdMedicine, err := h.medicineService.CreateMedicine(c.Request.Context(), req.toModel())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create medicine"})
+		c.Error(fmt.Errorf("failed to create medicine: %w", err))
 		return
 	}
 
-	// 3. Отправляем сообщение в Kafka
-	h.sendMedicineEventToKafka("medicine.created", int(createdMedicine.ID), c.GetString("username")) // Предположим, что имя пользователя есть в контексте
-
-	// 4. Отправляем ответ
+	// 3. Отправляем ответ (событие medicine.created записано в outbox сервисом)
+	setETag(c, createdMedicine.Version)
 	c.JSON(http.StatusCreated, createdMedicine)
 }
 
@@ -59,109 +57,332 @@ func (h *MedicineHandler) GetMedicineByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid ID"))
 		return
 	}
-	medicine, err := h.medicineService.GetMedicineByID(id)
+	medicine, err := h.medicineService.GetMedicineByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get medicine"})
+		c.Error(fmt.Errorf("failed to get medicine: %w", err))
 		return
 	}
 
+	setETag(c, medicine.Version)
 	c.JSON(http.StatusOK, medicine)
 }
 
 // GetAllMedicines - получает список всех лекарств
 func (h *MedicineHandler) GetAllMedicines(c *gin.Context) {
-	medicines, err := h.medicineService.GetAllMedicines()
+	filter, err := parseMedicineFilter(c)
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+	medicines, err := h.medicineService.GetAllMedicines(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get all medicines"})
+		c.Error(fmt.Errorf("failed to get all medicines: %w", err))
 		return
 	}
 	c.JSON(http.StatusOK, medicines)
 }
 
-// UpdateMedicine - обновляет информацию о лекарстве
+// ExportMedicines - выгружает каталог с остатками (format=csv|xlsx|jsonl).
+// Поддерживает те же фильтры, что и GetAllMedicines
+func (h *MedicineHandler) ExportMedicines(c *gin.Context) {
+	format, err := catalog.ParseFormat(c.DefaultQuery("format", string(catalog.FormatCSV)))
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+	filter, err := parseMedicineFilter(c)
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// Заголовки файла уходят клиенту вместе с первыми байтами. Пока ничего не отправлено
+	// (сбой первого запроса, XLSX собирается целиком), ошибку можно вернуть обычным ответом
+	out := &exportResponse{
+		c:           c,
+		contentType: format.ContentType(),
+		filename:    fmt.Sprintf("medicines-%s.%s", time.Now().UTC().Format("20060102-150405"), format),
+	}
+	buf := bufio.NewWriterSize(out, exportBufferSize)
+	err = h.writeExport(c.Request.Context(), buf, format, filter)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err == nil {
+		return
+	}
+	if !out.started {
+		c.Error(fmt.Errorf("failed to export medicines: %w", err))
+		return
+	}
+	// Статус 200 и часть файла уже отправлены - обрываем соединение, чтобы клиент
+	// не принял обрезанный файл за целый (см. middleware.Recovery)
+	slog.ErrorContext(c.Request.Context(), "Failed to export medicines", "error", err)
+	panic(http.ErrAbortHandler)
+}
+
+// exportBufferSize - сколько экспорта копится до отправки первых байтов клиенту
+const exportBufferSize = 64 << 10
+
+// writeExport записывает каталог в w в заданном формате
+func (h *MedicineHandler) writeExport(ctx context.Context, w io.Writer, format catalog.Format, filter models.MedicineFilter) error {
+	writer, err := catalog.NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+	if err := h.medicineService.StreamMedicines(ctx, filter, writer.Write); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// exportResponse отправляет заголовки файла перед первыми байтами тела
+type exportResponse struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (r *exportResponse) Write(p []byte) (int, error) {
+	if !r.started {
+		r.started = true
+		r.c.Header("Content-Type", r.contentType)
+		r.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", r.filename))
+		r.c.Status(http.StatusOK)
+	}
+	return r.c.Writer.Write(p)
+}
+
+// parseMedicineFilter читает фильтры списка из query: name, barcode, min_price, max_price, in_stock
+func parseMedicineFilter(c *gin.Context) (models.MedicineFilter, error) {
+	filter := models.MedicineFilter{
+		Name:    c.Query("name"),
+		Barcode: c.Query("barcode"),
+	}
+	if v := c.Query("min_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return filter, errors.New("invalid min_price")
+		}
+		filter.MinPrice = &price
+	}
+	if v := c.Query("max_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return filter, errors.New("invalid max_price")
+		}
+		filter.MaxPrice = &price
+	}
+	if v := c.Query("in_stock"); v != "" {
+		inStock, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("invalid in_stock")
+		}
+		filter.InStock = &inStock
+	}
+	return filter, nil
+}
+
+// UpdateMedicine - полностью заменяет информацию о лекарстве (PUT)
 func (h *MedicineHandler) UpdateMedicine(c *gin.Context) {
     idStr := c.Param("id")
     id, err := strconv.Atoi(idStr)
     if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+        c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid ID"))
         return
     }
-    var medicine models.Medicine
-    if err := c.ShouldBindJSON(&medicine); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    version, ok := ifMatchVersion(c)
+    if !ok {
+        return
+    }
+    var req MedicineRequest
+    if !bindJSON(c, &req) {
         return
     }
 
-    updatedMedicine, err := h.medicineService.UpdateMedicine(id, medicine)
+    opts := services.UpdateOptions{KeepReorderPoint: req.ReorderPoint == nil}
+    updatedMedicine, err := h.medicineService.UpdateMedicine(c.Request.Context(), id, req.toModel(), version, opts)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medicine"})
+        c.Error(fmt.Errorf("failed to update medicine: %w", err))
         return
     }
 
     // Проверяем, что updatedMedicine не является пустым значением
     if updatedMedicine.ID == 0 { // Или другая подходящая проверка
-        log.Println("updatedMedicine has zero value!")
+        slog.WarnContext(c.Request.Context(), "Updated medicine has zero value")
         return // Или обработайте ошибку другим способом
     }
 
-    h.sendMedicineEventToKafka("medicine.updated", int(updatedMedicine.ID), c.GetString("username")) // Предположим, что имя пользователя есть в контексте
-
+    setETag(c, updatedMedicine.Version)
     c.JSON(http.StatusOK, updatedMedicine)
 }
 
+// maxPatchSize - максимальный размер тела PATCH-запроса
+const maxPatchSize = 1 << 20
+
+// PatchMedicine - частично обновляет лекарство (PATCH, JSON Merge Patch RFC 7396)
+func (h *MedicineHandler) PatchMedicine(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid ID"))
+		return
+	}
+
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	switch c.ContentType() {
+	case "application/merge-patch+json", "application/json":
+	default:
+		c.Error(middleware.NewHTTPError(http.StatusUnsupportedMediaType, "Content-Type must be application/merge-patch+json"))
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchSize))
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Failed to read request body"))
+		return
+	}
+	if !json.Valid(patch) {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid JSON"))
+		return
+	}
+
+	patchedMedicine, err := h.medicineService.PatchMedicine(c.Request.Context(), id, patch, version)
+	if err != nil {
+		c.Error(fmt.Errorf("failed to update medicine: %w", err))
+		return
+	}
+
+	setETag(c, patchedMedicine.Version)
+	c.JSON(http.StatusOK, patchedMedicine)
+}
+
 // DeleteMedicine - удаляет лекарство
 func (h *MedicineHandler) DeleteMedicine(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid ID"))
 		return
 	}
-	err = h.medicineService.DeleteMedicine(id)
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	err = h.medicineService.DeleteMedicine(c.Request.Context(), id, version)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete medicine"})
+		c.Error(fmt.Errorf("failed to delete medicine: %w", err))
 		return
 	}
-	h.sendMedicineEventToKafka("medicine.deleted", id, c.GetString("username")) // Предположим, что имя пользователя есть в контексте
 
 	c.Status(http.StatusNoContent)
 }
 
-//sendMedicineEventToKafka  отправляет сообщение
-func (h *MedicineHandler) sendMedicineEventToKafka(event string, medicineID int, username string) {
-
-	message := map[string]interface{}{
-		"event":       event,
-		"timestamp":   time.Now().Format(time.RFC3339),
-		"medicine_id": medicineID,
-		"user":        username,
+// GetDeletedMedicines - список лекарств в корзине
+func (h *MedicineHandler) GetDeletedMedicines(c *gin.Context) {
+	medicines, err := h.medicineService.GetDeletedMedicines(c.Request.Context())
+	if err != nil {
+		c.Error(fmt.Errorf("failed to get deleted medicines: %w", err))
+		return
 	}
+	c.JSON(http.StatusOK, medicines)
+}
 
-	messageJSON, err := json.Marshal(message)
+// RestoreMedicine - возвращает лекарство из корзины
+func (h *MedicineHandler) RestoreMedicine(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		log.Printf("Failed to marshal medicine event: %s", err)
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid ID"))
 		return
 	}
-	err = h.produceMessage(h.kafkaTopic, string(messageJSON))
+	medicine, err := h.medicineService.RestoreMedicine(c.Request.Context(), id)
 	if err != nil {
-		log.Printf("Failed to send medicine event to Kafka: %s", err)
+		c.Error(fmt.Errorf("failed to restore medicine: %w", err))
+		return
+	}
+
+	setETag(c, medicine.Version)
+	c.JSON(http.StatusOK, medicine)
+}
+
+// PurgeMedicine - навсегда удаляет лекарство из корзины (только для администраторов)
+func (h *MedicineHandler) PurgeMedicine(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid ID"))
+		return
+	}
+	if err := h.medicineService.PurgeMedicine(c.Request.Context(), id); err != nil {
+		c.Error(fmt.Errorf("failed to purge medicine: %w", err))
+		return
 	}
 
+	c.Status(http.StatusNoContent)
 }
 
-// produceMessage отправляет сообщение в Kafka (вынесено для удобства)
-func (h *MedicineHandler) produceMessage(topic string, message string) error {
-	err := h.kafkaProducer.Produce(&kafka.Message{
-		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
-		Value:          []byte(message),
-	}, nil)
+// maxImportFileSize - максимальный размер файла импорта
+const maxImportFileSize = 32 << 20
+
+// ImportMedicines - импортирует каталог из CSV/XLSX (multipart-поле "file").
+// Необязательные параметры: format (csv|xlsx, по умолчанию по расширению файла),
+// mapping (JSON {"поле": "заголовок колонки"}), dry_run=true (только показать изменения)
+func (h *MedicineHandler) ImportMedicines(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "File is required"))
+		return
+	}
+	defer file.Close()
+
+	var format catalog.Format
+	if f := c.DefaultPostForm("format", c.Query("format")); f != "" {
+		format, err = catalog.ParseFormat(f)
+	} else {
+		format, err = catalog.FormatFromFilename(header.Filename)
+	}
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	var mapping catalog.Mapping
+	if m := c.PostForm("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+			c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid mapping: " + err.Error()))
+			return
+		}
+	}
 
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
 	if err != nil {
-		return fmt.Errorf("failed to produce message: %w", err)
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid dry_run"))
+		return
 	}
 
-	return nil
-}
\ No newline at end of file
+	records, err := catalog.Read(file, format, mapping)
+	if err != nil {
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	report, err := h.medicineService.ImportMedicines(c.Request.Context(), records, services.ImportOptions{DryRun: dryRun})
+	if err != nil {
+		c.Error(fmt.Errorf("failed to import medicines: %w", err))
+		return
+	}
+	if err := report.Err(); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
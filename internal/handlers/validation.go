@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"pharmacy-api/internal/middleware"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/services"
+)
+
+// MedicineRequest - тело запроса на создание и изменение лекарства
+type MedicineRequest struct {
+	Name         string   `json:"name" binding:"required,max=255"`
+	Description  string   `json:"description" binding:"max=2000"`
+	Barcode      string   `json:"barcode" binding:"max=64"`
+	Price        *float64 `json:"price" binding:"required,gte=0"`
+	Quantity     int      `json:"quantity" binding:"gte=0"`
+	ReorderPoint *int     `json:"reorder_point" binding:"omitempty,gte=0"` // не передан - 0 при создании, без изменений при PUT
+}
+
+// toModel переносит данные запроса в модель
+func (r MedicineRequest) toModel() models.Medicine {
+	medicine := models.Medicine{
+		Name:         r.Name,
+		Description:  r.Description,
+		Barcode:      r.Barcode,
+		Quantity:     r.Quantity,
+	}
+	if r.Price != nil {
+		medicine.Price = *r.Price
+	}
+	if r.ReorderPoint != nil {
+		medicine.ReorderPoint = *r.ReorderPoint
+	}
+	return medicine
+}
+
+func init() {
+	// В ошибках валидации используем имена полей из JSON, а не из Go-структур
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// bindJSON разбирает и проверяет тело запроса. При ошибке регистрирует ее
+// (400 для некорректного JSON, 422 для ошибок в полях) и возвращает false
+func bindJSON(c *gin.Context, dst interface{}) bool {
+	err := c.ShouldBindJSON(dst)
+	if err == nil {
+		return true
+	}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]services.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, services.FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+		}
+		c.Error(&services.ValidationError{Fields: fields})
+	case errors.As(err, &typeErr):
+		c.Error(&services.ValidationError{Fields: []services.FieldError{{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()}}})
+	default:
+		c.Error(middleware.NewHTTPError(http.StatusBadRequest, "Invalid request body: "+err.Error()))
+	}
+	return false
+}
+
+// validationMessage формирует понятное сообщение по тегу валидатора
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "min":
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	}
+	return "is invalid (" + fe.Tag() + ")"
+}
@@ -0,0 +1,94 @@
+// Package logging - структурированные логи log/slog. Атрибуты запроса или сообщения
+// (request_id, user_id, route, ...) хранятся в context и добавляются ко всем записям,
+// сделанным с этим контекстом: slog.InfoContext(ctx, ...) в сервисах и репозиториях
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Форматы вывода
+const (
+	FormatJSON = "json"
+	FormatText = "text" // для чтения глазами при локальном запуске
+)
+
+// attrsKey - ключ атрибутов в context
+type attrsKey struct{}
+
+// With возвращает ctx с дополнительными атрибутами (пары ключ-значение, как в slog.Logger.With)
+func With(ctx context.Context, args ...any) context.Context {
+	record := slog.NewRecord(time.Time{}, 0, "", 0)
+	record.Add(args...)
+	attrs := append([]slog.Attr(nil), Attrs(ctx)...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// Attrs возвращает атрибуты, добавленные в ctx через With
+func Attrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// New создает логгер, который пишет в w записи не ниже level
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q (use debug, info, warn or error)", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q (use json or text)", format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// Setup делает логгер New логгером по умолчанию для slog и стандартного пакета log
+func Setup(w io.Writer, level, format string) error {
+	logger, err := New(w, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// contextHandler добавляет к записи атрибуты из context и ID трассы OpenTelemetry
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		record.AddAttrs(Attrs(ctx)...)
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
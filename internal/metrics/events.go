@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"pharmacy-api/internal/events"
+)
+
+var eventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "kafka",
+	Name:      "messages_published_total",
+	Help:      "Messages sent to Kafka by topic and result (success or failure).",
+}, []string{"topic", "result"})
+
+// publisher считает отправленные и неотправленные сообщения
+type publisher struct {
+	events.Publisher
+}
+
+// InstrumentPublisher оборачивает p счетчиками отправленных сообщений
+func InstrumentPublisher(p events.Publisher) events.Publisher {
+	return publisher{Publisher: p}
+}
+
+// Publish отправляет сообщения; при ошибке все сообщения пачки считаются неотправленными
+func (p publisher) Publish(ctx context.Context, messages ...events.Message) error {
+	err := p.Publisher.Publish(ctx, messages...)
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	for _, m := range messages {
+		eventsPublished.WithLabelValues(m.Topic, result).Inc()
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+)
+
+var (
+	medicinesDesc = prometheus.NewDesc(namespace+"_medicines",
+		"Medicines in the catalog, excluding the trash.", nil, nil)
+	stockValueDesc = prometheus.NewDesc(namespace+"_stock_value",
+		"Total value of stock: sum of price * quantity.", nil, nil)
+	belowReorderDesc = prometheus.NewDesc(namespace+"_medicines_below_reorder_point",
+		"Medicines whose quantity is at or below their reorder point.", nil, nil)
+	outboxPendingDesc = prometheus.NewDesc(namespace+"_outbox_pending_messages",
+		"Events waiting in the outbox to be sent to Kafka.", nil, nil)
+)
+
+const (
+	// collectTimeout ограничивает запросы к базе при одном сборе метрик
+	collectTimeout = 5 * time.Second
+	// inventoryCacheTTL - сколько отдаются уже посчитанные показатели: частые опросы /metrics
+	// (несколько Prometheus, ручные запросы) не запускают агрегирующие запросы к базе каждый раз
+	inventoryCacheTTL = 30 * time.Second
+)
+
+// inventoryCollector считает показатели каталога и очередь outbox запросами к базе
+// не чаще раза в inventoryCacheTTL
+type inventoryCollector struct {
+	medicines repositories.MedicineRepository
+	outbox    repositories.OutboxRepository
+
+	mu          sync.Mutex
+	collectedAt time.Time
+	stats       *models.InventoryStats // nil - не удалось получить
+	pending     *int64
+}
+
+// RegisterInventory регистрирует бизнес-показатели каталога и глубину очереди outbox
+func RegisterInventory(medicines repositories.MedicineRepository, outbox repositories.OutboxRepository) error {
+	return Registry.Register(&inventoryCollector{medicines: medicines, outbox: outbox})
+}
+
+func (c *inventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- medicinesDesc
+	ch <- stockValueDesc
+	ch <- belowReorderDesc
+	ch <- outboxPendingDesc
+}
+
+// Collect пропускает показатели, которые не удалось получить: пропуск серии виден в Prometheus,
+// а нулевое значение выглядело бы как настоящее
+func (c *inventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	// Одновременные опросы ждут один запрос к базе
+	c.mu.Lock()
+	if time.Since(c.collectedAt) >= inventoryCacheTTL {
+		c.refresh()
+	}
+	stats, pending := c.stats, c.pending
+	c.mu.Unlock()
+
+	if stats != nil {
+		ch <- prometheus.MustNewConstMetric(medicinesDesc, prometheus.GaugeValue, float64(stats.Medicines))
+		ch <- prometheus.MustNewConstMetric(stockValueDesc, prometheus.GaugeValue, stats.StockValue)
+		ch <- prometheus.MustNewConstMetric(belowReorderDesc, prometheus.GaugeValue, float64(stats.BelowReorderPoint))
+	}
+	if pending != nil {
+		ch <- prometheus.MustNewConstMetric(outboxPendingDesc, prometheus.GaugeValue, float64(*pending))
+	}
+}
+
+// refresh запрашивает показатели из базы; вызывается под c.mu
+func (c *inventoryCollector) refresh() {
+	// promhttp не передает context запроса; сбор ограничен, чтобы не пережить таймаут опроса Prometheus
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	c.stats, c.pending = nil, nil
+	if stats, err := c.medicines.InventoryStats(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to collect inventory metrics", "error", err)
+	} else {
+		c.stats = &stats
+	}
+	if pending, err := c.outbox.CountPending(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to collect outbox metrics", "error", err)
+	} else {
+		c.pending = &pending
+	}
+	c.collectedAt = time.Now()
+}
@@ -0,0 +1,69 @@
+// Package metrics - метрики Prometheus: HTTP-запросы, запросы к базе, публикация событий
+// и бизнес-показатели каталога. Отдаются по GET /metrics (см. Handler)
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace - префикс имен всех метрик приложения
+const namespace = "pharmacy"
+
+// Registry - реестр метрик приложения, включая метрики рантайма Go и процесса
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, httpInFlight,
+		dbQueryDuration, dbQueryErrors,
+		eventsPublished,
+	)
+}
+
+// Handler отдает метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// HTTPRequestStarted учитывает начало запроса и возвращает функцию, которую нужно вызвать по его завершении.
+// route - шаблон маршрута (/medicines/:id), а не путь, чтобы число серий не зависело от ID
+func HTTPRequestStarted() func(method, route, status string) {
+	httpInFlight.Inc()
+	start := time.Now()
+	return func(method, route, status string) {
+		httpInFlight.Dec()
+		httpRequests.WithLabelValues(method, route, status).Inc()
+		httpDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
     "net/http"
     
+    "pharmacy-api/internal/logging"
     "pharmacy-api/internal/services"
     "strings"
 
@@ -13,19 +14,36 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
     return func(c *gin.Context) {
         authHeader := c.GetHeader("Authorization")
         if authHeader == "" {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+            AbortWithProblem(c, Problem{Status: http.StatusUnauthorized, Detail: "Authorization header required"})
             return
         }
 
         tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 
-        claims, err := authService.ValidateToken(tokenString)
+        claims, err := authService.ValidateToken(c.Request.Context(), tokenString)
         if err != nil {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+            AbortWithProblem(c, Problem{Status: http.StatusUnauthorized, Detail: "Invalid token"})
             return
         }
 
         c.Set("userID", claims.UserID) // Сохраняем ID пользователя в контексте
+        c.Set("username", claims.Username)
+        c.Set("role", claims.Role)
+        // Сервисам пользователь передается через context запроса (автор изменений в событиях)
+        // и в логи запроса
+        ctx := services.WithActor(c.Request.Context(), claims.UserID)
+        c.Request = c.Request.WithContext(logging.With(ctx, "user_id", claims.UserID))
+        c.Next()
+    }
+}
+
+// RequireRole пропускает только пользователей с заданной ролью; ставится после AuthMiddleware
+func RequireRole(role string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if c.GetString("role") != role {
+            AbortWithProblem(c, Problem{Status: http.StatusForbidden, Detail: "Requires role " + role})
+            return
+        }
         c.Next()
     }
 }
@@ -34,8 +52,9 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*") // Разрешаем запросы от любого источника
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-Match, X-Correlation-ID, X-Request-ID, Idempotency-Key")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, X-Correlation-ID, X-Request-ID, Idempotent-Replayed, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"strings"
+
+	"pharmacy-api/internal/logging"
+	"pharmacy-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CorrelationIDHeader - заголовок с ID цепочки запросов
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// maxCorrelationIDLength ограничивает длину ID, пришедшего от клиента
+const maxCorrelationIDLength = 128
+
+// CorrelationID берет ID цепочки запросов из заголовка X-Correlation-ID (или создает новый),
+// возвращает его в ответе и кладет в context запроса, откуда он попадает в события
+func CorrelationID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		correlationID := c.GetHeader(CorrelationIDHeader)
+		if !validCorrelationID(correlationID) {
+			correlationID = uuid.NewString()
+		}
+
+		c.Header(CorrelationIDHeader, correlationID)
+		ctx := services.WithCorrelationID(c.Request.Context(), correlationID)
+		c.Request = c.Request.WithContext(logging.With(ctx, "correlation_id", correlationID))
+		c.Next()
+	}
+}
+
+// validCorrelationID допускает непустые ID из букв, цифр и символов "-_.:"
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("-_.:", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"pharmacy-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Problem - тело ошибки по RFC 7807 (application/problem+json)
+type Problem struct {
+	Type     string                 `json:"type"`
+	Title    string                 `json:"title"`
+	Status   int                    `json:"status"`
+	Detail   string                 `json:"detail,omitempty"`
+	Instance string                 `json:"instance,omitempty"`
+	Errors   []services.FieldError  `json:"errors,omitempty"` // ошибки по полям для 422
+	Report   *services.ImportReport `json:"report,omitempty"` // отчет импорта с ошибками по строкам для 422
+}
+
+// HTTPError - ошибка HTTP-слоя с заранее известным статусом
+// (некорректный ID в пути, отсутствующий заголовок и т.п.)
+type HTTPError struct {
+	Status int
+	Detail string
+}
+
+func (e *HTTPError) Error() string {
+	return e.Detail
+}
+
+// NewHTTPError создает ошибку с заданным статусом
+func NewHTTPError(status int, detail string) *HTTPError {
+	return &HTTPError{Status: status, Detail: detail}
+}
+
+// StatusClientClosedRequest - клиент закрыл соединение, не дождавшись ответа (как в nginx).
+// Ответ никто не получит, статус нужен для логов и метрик
+const StatusClientClosedRequest = 499
+
+// problemTypes - URI типа проблемы по статусу; для остальных статусов "about:blank"
+var problemTypes = map[int]string{
+	http.StatusBadRequest:           "/problems/bad-request",
+	http.StatusUnauthorized:         "/problems/unauthorized",
+	http.StatusForbidden:            "/problems/forbidden",
+	http.StatusNotFound:             "/problems/not-found",
+	http.StatusConflict:             "/problems/conflict",
+	http.StatusPreconditionFailed:   "/problems/precondition-failed",
+	http.StatusUnprocessableEntity:  "/problems/validation-error",
+	http.StatusPreconditionRequired: "/problems/precondition-required",
+	http.StatusTooManyRequests:      "/problems/too-many-requests",
+}
+
+// ErrorHandler отображает ошибки, добавленные обработчиками через c.Error, в ответ problem+json.
+// Обработчикам не нужно знать HTTP-статусы доменных ошибок: достаточно вызвать c.Error(err) и выйти
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		problem := problemFor(err)
+		switch problem.Status {
+		case http.StatusInternalServerError:
+			slog.ErrorContext(c.Request.Context(), "Request failed", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+		case http.StatusServiceUnavailable:
+			slog.WarnContext(c.Request.Context(), "Request timed out", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+		}
+		problem.Instance = c.Request.URL.Path
+		AbortWithProblem(c, problem)
+	}
+}
+
+// AbortWithProblem прерывает запрос и отправляет problem+json
+func AbortWithProblem(c *gin.Context, problem Problem) {
+	if problem.Type == "" {
+		problem.Type = problemType(problem.Status)
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// problemFor сопоставляет ошибку со статусом ответа
+func problemFor(err error) Problem {
+	var (
+		httpErr       *HTTPError
+		validationErr *services.ValidationError
+		importErr     *services.ImportError
+		notFoundErr   *services.NotFoundError
+		conflictErr   *services.ConflictError
+		forbiddenErr  *services.ForbiddenError
+	)
+	switch {
+	case errors.As(err, &httpErr):
+		return Problem{Status: httpErr.Status, Detail: httpErr.Detail}
+	case errors.As(err, &validationErr):
+		return Problem{Status: http.StatusUnprocessableEntity, Detail: "Request contains invalid fields", Errors: validationErr.Fields}
+	case errors.As(err, &importErr):
+		return Problem{Status: http.StatusUnprocessableEntity, Detail: "File contains invalid rows, nothing was imported", Report: &importErr.Report}
+	case errors.As(err, &notFoundErr):
+		return Problem{Status: http.StatusNotFound, Detail: notFoundErr.Error()}
+	case errors.As(err, &conflictErr):
+		return Problem{Status: http.StatusConflict, Detail: conflictErr.Error()}
+	case errors.As(err, &forbiddenErr):
+		return Problem{Status: http.StatusForbidden, Detail: forbiddenErr.Error()}
+	case errors.Is(err, services.ErrVersionMismatch):
+		return Problem{Status: http.StatusPreconditionFailed, Detail: "Resource was modified by another request, fetch it again and retry"}
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return Problem{Status: http.StatusUnauthorized, Detail: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded):
+		return Problem{Type: "/problems/timeout", Status: http.StatusServiceUnavailable, Detail: "Request took too long and was cancelled, retry later"}
+	case errors.Is(err, context.Canceled):
+		return Problem{Status: StatusClientClosedRequest}
+	}
+	// Детали внутренних ошибок клиенту не показываем
+	return Problem{Status: http.StatusInternalServerError}
+}
+
+func problemType(status int) string {
+	if t, ok := problemTypes[status]; ok {
+		return t
+	}
+	return "about:blank"
+}
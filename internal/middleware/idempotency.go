@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader - заголовок с ключом идемпотентности
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader отмечает ответ, повторенный из сохраненного
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize - тело запроса читается в память для хеширования
+	maxIdempotentBodySize = 64 << 20
+)
+
+// replayedHeaders - заголовки ответа, которые сохраняются вместе с телом
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// Idempotency делает POST-запросы с заголовком Idempotency-Key безопасными для повтора.
+// Первый запрос выполняется, а его ответ (если это не ошибка сервера) сохраняется на ttl.
+// Повтор с тем же ключом и тем же телом получает сохраненный ответ без повторного выполнения,
+// повтор с другим телом - 422, повтор во время выполнения исходного запроса - 409.
+// Ключи разделены по пользователям, поэтому middleware ставится после AuthMiddleware
+func Idempotency(store repositories.IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			AbortWithProblem(c, Problem{Status: http.StatusBadRequest, Detail: IdempotencyKeyHeader + " is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentBodySize+1))
+		if err != nil {
+			AbortWithProblem(c, Problem{Status: http.StatusBadRequest, Detail: "Failed to read request body"})
+			return
+		}
+		if len(body) > maxIdempotentBodySize {
+			AbortWithProblem(c, Problem{Status: http.StatusRequestEntityTooLarge, Detail: "Request body is too large"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := &models.IdempotencyKey{
+			Key:         fmt.Sprintf("%d:%s", c.GetUint("userID"), key),
+			RequestHash: requestHash(c.Request, body),
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+		existing, err := store.Reserve(c.Request.Context(), record)
+		if errors.Is(err, repositories.ErrDuplicate) {
+			replayIdempotent(c, record, existing)
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to reserve idempotency key", "error", err)
+			AbortWithProblem(c, Problem{Status: http.StatusInternalServerError})
+			return
+		}
+
+		// Ответ сохраняется и ключ освобождается, даже если запрос отменен или истек его таймаут
+		storeCtx := context.WithoutCancel(c.Request.Context())
+		writer := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		defer func() {
+			// Ошибки сервера и паника: ключ освобождается, чтобы клиент мог повторить запрос
+			if !completed {
+				if err := store.Release(storeCtx, record.Key); err != nil {
+					slog.ErrorContext(storeCtx, "Failed to release idempotency key", "error", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		// Ошибки, добавленные через c.Error, ErrorHandler выводит уже после этого middleware;
+		// такие ответы не сохраняются, и повтор выполнит запрос заново
+		status := writer.Status()
+		if !writer.Written() || status >= http.StatusInternalServerError {
+			return
+		}
+		header := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if v := writer.Header().Get(name); v != "" {
+				header[name] = v
+			}
+		}
+		headerJSON, _ := json.Marshal(header)
+		if err := store.Complete(storeCtx, record.Key, status, string(headerJSON), writer.body.Bytes()); err != nil {
+			slog.ErrorContext(storeCtx, "Failed to store idempotent response", "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replayIdempotent отвечает на повтор запроса с уже использованным ключом
+func replayIdempotent(c *gin.Context, record, existing *models.IdempotencyKey) {
+	switch {
+	case existing.RequestHash != record.RequestHash:
+		AbortWithProblem(c, Problem{Status: http.StatusUnprocessableEntity, Detail: IdempotencyKeyHeader + " was already used with a different request"})
+	case existing.StatusCode == 0:
+		AbortWithProblem(c, Problem{Status: http.StatusConflict, Detail: "A request with this " + IdempotencyKeyHeader + " is still in progress"})
+	default:
+		var header map[string]string
+		if existing.Header != "" {
+			_ = json.Unmarshal([]byte(existing.Header), &header)
+		}
+		for name, value := range header {
+			c.Header(name, value)
+		}
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Status(existing.StatusCode)
+		_, _ = c.Writer.Write(existing.Body)
+		c.Abort()
+	}
+}
+
+// requestHash отличает повтор того же запроса от другого запроса с тем же ключом
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// captureWriter копирует тело ответа для сохранения
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
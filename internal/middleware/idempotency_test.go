@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pharmacy-api/internal/repositories/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// idempotencyRouter - POST /medicines, который считает выполнения и отвечает статусом status
+func idempotencyRouter(status *int, calls *int) *gin.Engine {
+	router := gin.New()
+	router.Use(Idempotency(memory.NewIdempotencyStore(), time.Hour))
+	router.POST("/medicines", func(c *gin.Context) {
+		*calls++
+		c.Header("Location", "/medicines/1")
+		c.JSON(*status, gin.H{"call": *calls})
+	})
+	return router
+}
+
+func postMedicine(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/medicines", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency(t *testing.T) {
+	type request struct {
+		key, body   string
+		wantStatus  int
+		wantBody    string
+		wantReplay  bool
+		wantHandled int // сколько раз обработчик выполнен после запроса
+	}
+	tests := []struct {
+		name     string
+		status   int
+		requests []request
+	}{
+		{
+			name:   "replay returns stored response",
+			status: http.StatusCreated,
+			requests: []request{
+				{key: "k1", body: `{"name":"Aspirin"}`, wantStatus: http.StatusCreated, wantBody: `{"call":1}`, wantHandled: 1},
+				{key: "k1", body: `{"name":"Aspirin"}`, wantStatus: http.StatusCreated, wantBody: `{"call":1}`, wantReplay: true, wantHandled: 1},
+			},
+		},
+		{
+			name:   "different body is a conflict",
+			status: http.StatusCreated,
+			requests: []request{
+				{key: "k1", body: `{"name":"Aspirin"}`, wantStatus: http.StatusCreated, wantHandled: 1},
+				{key: "k1", body: `{"name":"Ibuprofen"}`, wantStatus: http.StatusUnprocessableEntity, wantHandled: 1},
+			},
+		},
+		{
+			name:   "different keys are independent",
+			status: http.StatusCreated,
+			requests: []request{
+				{key: "k1", body: `{}`, wantStatus: http.StatusCreated, wantHandled: 1},
+				{key: "k2", body: `{}`, wantStatus: http.StatusCreated, wantBody: `{"call":2}`, wantHandled: 2},
+			},
+		},
+		{
+			name:   "without key every request runs",
+			status: http.StatusCreated,
+			requests: []request{
+				{body: `{}`, wantStatus: http.StatusCreated, wantHandled: 1},
+				{body: `{}`, wantStatus: http.StatusCreated, wantHandled: 2},
+			},
+		},
+		{
+			name:   "server error is not stored",
+			status: http.StatusServiceUnavailable,
+			requests: []request{
+				{key: "k1", body: `{}`, wantStatus: http.StatusServiceUnavailable, wantHandled: 1},
+				{key: "k1", body: `{}`, wantStatus: http.StatusServiceUnavailable, wantBody: `{"call":2}`, wantHandled: 2},
+			},
+		},
+		{
+			name:   "key too long",
+			status: http.StatusCreated,
+			requests: []request{
+				{key: strings.Repeat("k", maxIdempotencyKeyLength+1), body: `{}`, wantStatus: http.StatusBadRequest},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			router := idempotencyRouter(&tt.status, &calls)
+			for i, r := range tt.requests {
+				w := postMedicine(router, r.key, r.body)
+				if w.Code != r.wantStatus {
+					t.Errorf("request %d: status = %d, want %d", i, w.Code, r.wantStatus)
+				}
+				if r.wantBody != "" && w.Body.String() != r.wantBody {
+					t.Errorf("request %d: body = %s, want %s", i, w.Body.String(), r.wantBody)
+				}
+				if replayed := w.Header().Get(IdempotentReplayedHeader) == "true"; replayed != r.wantReplay {
+					t.Errorf("request %d: replayed = %v, want %v", i, replayed, r.wantReplay)
+				}
+				if r.wantReplay && w.Header().Get("Location") != "/medicines/1" {
+					t.Errorf("request %d: Location = %q, want stored header", i, w.Header().Get("Location"))
+				}
+				if calls != r.wantHandled {
+					t.Errorf("request %d: handler ran %d times, want %d", i, calls, r.wantHandled)
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	router := gin.New()
+	router.Use(Idempotency(memory.NewIdempotencyStore(), time.Hour))
+	var nested *httptest.ResponseRecorder
+	router.POST("/medicines", func(c *gin.Context) {
+		// Повтор приходит, пока исходный запрос еще выполняется
+		if nested == nil {
+			nested = postMedicine(router, "k1", `{}`)
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	if w := postMedicine(router, "k1", `{}`); w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if nested.Code != http.StatusConflict {
+		t.Errorf("concurrent retry status = %d, want %d", nested.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"pharmacy-api/internal/metrics"
+)
+
+// Metrics учитывает количество и длительность запросов по маршруту и статусу ответа.
+// Запросы к несуществующим маршрутам учитываются под route="unmatched".
+// Запрос, обработчик которого запаниковал, учитывается со статусом 500, и паника передается дальше
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done := metrics.HTTPRequestStarted()
+		defer func() {
+			status := c.Writer.Status()
+			rec := recover()
+			if rec != nil {
+				status = http.StatusInternalServerError
+			}
+
+			route := c.FullPath()
+			if route == "" {
+				route = "unmatched"
+			}
+			done(c.Request.Method, route, strconv.Itoa(status))
+
+			if rec != nil {
+				panic(rec)
+			}
+		}()
+		c.Next()
+	}
+}
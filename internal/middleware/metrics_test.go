@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"pharmacy-api/internal/metrics"
+)
+
+func TestMetricsPanic(t *testing.T) {
+	tests := []struct {
+		name  string
+		panic any
+	}{
+		{name: "panic", panic: "boom"},
+		{name: "aborted response", panic: http.ErrAbortHandler},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Metrics())
+			router.GET("/panic", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+				c.Writer.WriteHeaderNow()
+				panic(tt.panic)
+			})
+
+			before := inFlight(t)
+			func() {
+				defer func() {
+					if rec := recover(); rec != tt.panic {
+						t.Errorf("recovered %v, want the handler panic %v", rec, tt.panic)
+					}
+				}()
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+			}()
+			if after := inFlight(t); after != before {
+				t.Errorf("requests_in_flight = %v after the panic, want %v", after, before)
+			}
+			if n := requests(t, "/panic", "500"); n != float64(i+1) {
+				t.Errorf("requests_total{status=\"500\"} = %v, want %v", n, i+1)
+			}
+		})
+	}
+}
+
+func inFlight(t *testing.T) float64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == "pharmacy_http_requests_in_flight" {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatal("requests_in_flight is not registered")
+	return 0
+}
+
+// requests возвращает счетчик requests_total запросов GET к route со статусом status
+func requests(t *testing.T, route, status string) float64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "pharmacy_http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["method"] == http.MethodGet && labels["route"] == route && labels["status"] == status {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitPolicy - лимиты группы маршрутов
+type RateLimitPolicy struct {
+	Scope string           // префикс ключа корзины: у групп маршрутов независимые лимиты
+	Read  models.RateLimit // GET и HEAD
+	Write models.RateLimit // остальные методы
+}
+
+// RateLimit ограничивает частоту запросов клиента (token bucket). Клиент - пользователь,
+// если middleware стоит после AuthMiddleware, иначе IP. Ответ содержит заголовки RateLimit-*;
+// при превышении - 429 и Retry-After. Если store недоступен, запросы пропускаются
+func RateLimit(store repositories.RateLimitStore, policy RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		class, limit := "write", policy.Write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			class, limit = "read", policy.Read
+		}
+
+		key := policy.Scope + ":" + class + ":" + clientKey(c)
+		result, err := store.Take(c.Request.Context(), key, limit, time.Now())
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Rate limiter is unavailable, allowing request", "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Burst, ceilSeconds(limit.Window())))
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", retryAfter)
+			AbortWithProblem(c, Problem{Status: http.StatusTooManyRequests, Detail: "Rate limit exceeded, retry in " + retryAfter + " seconds"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// clientKey выбирает, по кому считается лимит: пользователь или IP.
+// Ключа по API-ключу нет намеренно: API-ключи в приложении не проверяются, а лимит по
+// непроверенному заголовку клиент обходил бы, меняя ключ в каждом запросе. Когда появится
+// аутентификация по API-ключу, ее middleware должен класть ID ключа в контекст, и лимит
+// будет считаться по нему так же, как по userID
+func clientKey(c *gin.Context) string {
+	if userID := c.GetUint("userID"); userID != 0 {
+		return "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
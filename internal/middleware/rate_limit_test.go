@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+	"pharmacy-api/internal/repositories/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+func rateLimitRouter(store repositories.RateLimitStore, userID uint) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("userID", userID)
+		}
+	})
+	router.Use(RateLimit(store, RateLimitPolicy{
+		Scope: "api",
+		Read:  models.RateLimit{Rate: 1, Burst: 2},
+		Write: models.RateLimit{Rate: 1, Burst: 1},
+	}))
+	handler := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/medicines", handler)
+	router.POST("/medicines", handler)
+	return router
+}
+
+func serveRateLimited(router http.Handler, method, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/medicines", nil)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	router := rateLimitRouter(memory.NewRateLimitStore(), 0)
+
+	tests := []struct {
+		name          string
+		method        string
+		remoteAddr    string
+		wantStatus    int
+		wantRemaining string
+	}{
+		{name: "first read", method: http.MethodGet, remoteAddr: "10.0.0.1:1000", wantStatus: http.StatusNoContent, wantRemaining: "1"},
+		{name: "second read", method: http.MethodGet, remoteAddr: "10.0.0.1:1001", wantStatus: http.StatusNoContent, wantRemaining: "0"},
+		{name: "read limit exceeded", method: http.MethodGet, remoteAddr: "10.0.0.1:1002", wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+		{name: "writes are limited separately", method: http.MethodPost, remoteAddr: "10.0.0.1:1003", wantStatus: http.StatusNoContent, wantRemaining: "0"},
+		{name: "another client has its own bucket", method: http.MethodGet, remoteAddr: "10.0.0.2:1000", wantStatus: http.StatusNoContent, wantRemaining: "1"},
+	}
+	for _, tt := range tests {
+		w := serveRateLimited(router, tt.method, tt.remoteAddr)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantStatus)
+		}
+		if got := w.Header().Get("RateLimit-Remaining"); got != tt.wantRemaining {
+			t.Errorf("%s: RateLimit-Remaining = %q, want %q", tt.name, got, tt.wantRemaining)
+		}
+		if w.Header().Get("RateLimit-Limit") == "" || w.Header().Get("RateLimit-Policy") == "" {
+			t.Errorf("%s: RateLimit-Limit and RateLimit-Policy headers are missing", tt.name)
+		}
+		if retryAfter := w.Header().Get("Retry-After"); (tt.wantStatus == http.StatusTooManyRequests) != (retryAfter != "") {
+			t.Errorf("%s: Retry-After = %q", tt.name, retryAfter)
+		}
+	}
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	store := memory.NewRateLimitStore()
+	user := rateLimitRouter(store, 7)
+	other := rateLimitRouter(store, 8)
+
+	// Лимит пользователя не зависит от адреса, с которого он обращается
+	serveRateLimited(user, http.MethodPost, "10.0.0.1:1000")
+	if w := serveRateLimited(user, http.MethodPost, "10.0.0.2:1000"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("same user from another IP: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w := serveRateLimited(other, http.MethodPost, "10.0.0.1:1000"); w.Code != http.StatusNoContent {
+		t.Errorf("another user from the same IP: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
+
+// failingRateLimitStore - недоступное хранилище лимитов
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.RateLimitResult, error) {
+	return models.RateLimitResult{}, errors.New("database unavailable")
+}
+
+func (failingRateLimitStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestRateLimitStoreUnavailable(t *testing.T) {
+	router := rateLimitRouter(failingRateLimitStore{}, 0)
+	for i := 0; i < 3; i++ {
+		if w := serveRateLimited(router, http.MethodPost, "10.0.0.1:1000"); w.Code != http.StatusNoContent {
+			t.Fatalf("request %d: status = %d, want %d", i, w.Code, http.StatusNoContent)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery перехватывает панику обработчика, пишет ее в лог и отвечает 500.
+// panic(http.ErrAbortHandler) пропускается дальше: net/http обрывает соединение, и клиент
+// видит, что ответ, начатый со статусом 200, не дописан (например, при ошибке посреди экспорта)
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				slog.WarnContext(c.Request.Context(), "Response aborted", "method", c.Request.Method, "path", c.Request.URL.Path)
+				panic(rec)
+			}
+			slog.ErrorContext(c.Request.Context(), "Panic recovered", "method", c.Request.Method, "path", c.Request.URL.Path,
+				"panic", rec, "stack", string(debug.Stack()))
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			AbortWithProblem(c, Problem{Status: http.StatusInternalServerError})
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"pharmacy-api/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader - заголовок с ID запроса
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID берет ID запроса из заголовка X-Request-ID (или создает новый) и возвращает его в ответе.
+// ID запроса и маршрут добавляются в context, поэтому попадают во все логи, записанные во время запроса
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validCorrelationID(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		args := []any{"request_id", requestID}
+		if route := c.FullPath(); route != "" {
+			args = append(args, "route", route)
+		}
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), args...))
+		c.Next()
+	}
+}
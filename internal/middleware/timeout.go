@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout ограничивает время обработки запроса: по истечении таймаута context запроса отменяется,
+// и запросы к базе и публикация событий прерываются (ответ 503, см. ErrorHandler).
+// routes задает таймаут отдельных маршрутов по шаблону gin ("/medicines/import"); 0 - без ограничения
+func Timeout(timeout time.Duration, routes map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := timeout
+		if routeTimeout, ok := routes[c.FullPath()]; ok {
+			d = routeTimeout
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+-- Исходная схема. IF NOT EXISTS - базы, созданные до появления миграций, принимаются как есть
+CREATE TABLE IF NOT EXISTS users (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    username   TEXT NOT NULL,
+    password   TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS medicines (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    name        TEXT NOT NULL,
+    description TEXT,
+    price       DECIMAL NOT NULL,
+    quantity    BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_medicines_deleted_at ON medicines (deleted_at);
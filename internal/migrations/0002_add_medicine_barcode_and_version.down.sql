@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_medicines_barcode;
+ALTER TABLE medicines
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS barcode;
@@ -0,0 +1,4 @@
+ALTER TABLE medicines
+    ADD COLUMN IF NOT EXISTS barcode TEXT,
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_medicines_barcode ON medicines (barcode);
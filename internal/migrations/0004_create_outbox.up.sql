@@ -0,0 +1,13 @@
+CREATE TABLE outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    topic           TEXT NOT NULL,
+    key             TEXT,
+    payload         JSONB NOT NULL,
+    attempts        BIGINT NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    sent_at         TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ
+);
+CREATE INDEX idx_outbox_next_attempt_at ON outbox (next_attempt_at);
+CREATE INDEX idx_outbox_sent_at ON outbox (sent_at);
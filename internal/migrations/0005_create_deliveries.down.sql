@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS unmatched_delivery_items;
+DROP TABLE IF EXISTS stock_receipts;
+DROP TABLE IF EXISTS processed_deliveries;
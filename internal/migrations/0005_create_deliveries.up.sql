@@ -0,0 +1,35 @@
+CREATE TABLE processed_deliveries (
+    delivery_id  TEXT PRIMARY KEY,
+    supplier     TEXT NOT NULL,
+    item_count   BIGINT NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE stock_receipts (
+    id          BIGSERIAL PRIMARY KEY,
+    delivery_id TEXT NOT NULL,
+    medicine_id BIGINT NOT NULL,
+    supplier    TEXT NOT NULL,
+    barcode     TEXT,
+    lot         TEXT,
+    expiry_date DATE,
+    quantity    BIGINT NOT NULL,
+    created_at  TIMESTAMPTZ
+);
+CREATE INDEX idx_stock_receipts_delivery_id ON stock_receipts (delivery_id);
+CREATE INDEX idx_stock_receipts_medicine_id ON stock_receipts (medicine_id);
+
+CREATE TABLE unmatched_delivery_items (
+    id          BIGSERIAL PRIMARY KEY,
+    delivery_id TEXT NOT NULL,
+    supplier    TEXT NOT NULL,
+    barcode     TEXT,
+    lot         TEXT,
+    expiry_date DATE,
+    quantity    BIGINT NOT NULL,
+    medicine_id BIGINT,
+    resolved_at TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ
+);
+CREATE INDEX idx_unmatched_delivery_items_delivery_id ON unmatched_delivery_items (delivery_id);
+CREATE INDEX idx_unmatched_delivery_items_resolved_at ON unmatched_delivery_items (resolved_at);
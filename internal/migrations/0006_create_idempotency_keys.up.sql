@@ -0,0 +1,10 @@
+CREATE TABLE idempotency_keys (
+    key          TEXT PRIMARY KEY,
+    request_hash TEXT NOT NULL,
+    status_code  BIGINT NOT NULL DEFAULT 0,
+    header       JSONB,
+    body         BYTEA,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    created_at   TIMESTAMPTZ
+);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
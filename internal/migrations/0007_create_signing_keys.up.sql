@@ -0,0 +1,7 @@
+CREATE TABLE signing_keys (
+    kid        TEXT PRIMARY KEY,
+    secret     BYTEA NOT NULL,
+    created_at TIMESTAMPTZ,
+    retired_at TIMESTAMPTZ
+);
+CREATE INDEX idx_signing_keys_retired_at ON signing_keys (retired_at);
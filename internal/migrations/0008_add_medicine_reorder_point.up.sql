@@ -0,0 +1 @@
+ALTER TABLE medicines ADD COLUMN reorder_point BIGINT NOT NULL DEFAULT 0;
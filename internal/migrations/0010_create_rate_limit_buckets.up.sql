@@ -0,0 +1,7 @@
+CREATE TABLE rate_limit_buckets (
+    key         TEXT PRIMARY KEY,
+    tokens      DOUBLE PRECISION NOT NULL,
+    refilled_at TIMESTAMPTZ NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_rate_limit_buckets_expires_at ON rate_limit_buckets (expires_at);
@@ -0,0 +1,30 @@
+// Package migrations - SQL-миграции схемы базы данных.
+// Файлы называются <версия>_<название>.up.sql и <версия>_<название>.down.sql;
+// применяются по возрастанию версии (см. pkg/database/migrate)
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"log/slog"
+
+	"gorm.io/gorm"
+
+	"pharmacy-api/pkg/database/migrate"
+)
+
+// FS - встроенные в бинарник файлы миграций
+//
+//go:embed *.sql
+var FS embed.FS
+
+// New создает Migrator для схемы приложения
+func New(db *gorm.DB) (*migrate.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, FS, func(format string, args ...interface{}) {
+		slog.Info(fmt.Sprintf(format, args...))
+	})
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Таблицы поставок создает миграция 0005_create_deliveries
+
+// ProcessedDelivery - поставка, уже учтенная в остатках. Уникальный DeliveryID
+// не дает учесть одну поставку дважды при повторной доставке сообщения
+type ProcessedDelivery struct {
+    DeliveryID  string    `gorm:"primaryKey" json:"delivery_id"`
+    Supplier    string    `gorm:"not null" json:"supplier"`
+    ItemCount   int       `gorm:"not null" json:"item_count"`
+    ProcessedAt time.Time `gorm:"not null" json:"processed_at"`
+}
+
+// StockReceipt - приход товара на склад (строка поставки, сопоставленная с лекарством)
+type StockReceipt struct {
+    ID         uint       `gorm:"primaryKey" json:"id"`
+    DeliveryID string     `gorm:"not null;index" json:"delivery_id"`
+    MedicineID uint       `gorm:"not null;index" json:"medicine_id"`
+    Supplier   string     `gorm:"not null" json:"supplier"`
+    Barcode    string     `json:"barcode"`
+    Lot        string     `json:"lot"`
+    ExpiryDate *time.Time `gorm:"type:date" json:"expiry_date"`
+    Quantity   int        `gorm:"not null" json:"quantity"`
+    CreatedAt  time.Time  `json:"created_at"`
+}
+
+// UnmatchedDeliveryItem - строка поставки, для которой не нашлось лекарства по штрихкоду.
+// Ждет ручного сопоставления; после него ResolvedAt и MedicineID заполнены
+type UnmatchedDeliveryItem struct {
+    ID         uint       `gorm:"primaryKey" json:"id"`
+    DeliveryID string     `gorm:"not null;index" json:"delivery_id"`
+    Supplier   string     `gorm:"not null" json:"supplier"`
+    Barcode    string     `json:"barcode"`
+    Lot        string     `json:"lot"`
+    ExpiryDate *time.Time `gorm:"type:date" json:"expiry_date"`
+    Quantity   int        `gorm:"not null" json:"quantity"`
+    MedicineID *uint      `json:"medicine_id"`
+    ResolvedAt *time.Time `gorm:"index" json:"resolved_at"`
+    CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyKey - ответ на запрос с заголовком Idempotency-Key, сохраненный для повторов.
+// StatusCode = 0, пока исходный запрос еще выполняется. Таблица - миграция 0006_create_idempotency_keys
+type IdempotencyKey struct {
+    Key         string    `gorm:"primaryKey" json:"key"`
+    RequestHash string    `gorm:"not null" json:"request_hash"`
+    StatusCode  int       `gorm:"not null;default:0" json:"status_code"`
+    Header      string    `gorm:"type:jsonb" json:"header"` // сохраненные заголовки ответа (JSON)
+    Body        []byte    `json:"body"`
+    ExpiresAt   time.Time `gorm:"not null;index" json:"expires_at"`
+    CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// InventoryStats - сводка по остаткам каталога (без лекарств в корзине)
+type InventoryStats struct {
+    Medicines         int64   // количество лекарств в каталоге
+    StockValue        float64 // сумма price * quantity
+    BelowReorderPoint int64   // лекарства с заданной точкой заказа, остаток которых не выше нее
+}
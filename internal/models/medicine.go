@@ -5,8 +5,11 @@ import "gorm.io/gorm"
 type Medicine struct {
     gorm.Model
     //ID    int    `json:"id" gorm:"primaryKey;autoIncrement"`
-    Name        string  `gorm:"not null" json:"name"`
-    Description string  `json:"description"`
-    Price       float64 `gorm:"not null" json:"price"`
-    Quantity    int     `gorm:"not null" json:"quantity"`
+    Name         string  `gorm:"not null" json:"name"`
+    Description  string  `json:"description"`
+    Barcode      string  `gorm:"index" json:"barcode"`
+    Price        float64 `gorm:"not null" json:"price"`
+    Quantity     int     `gorm:"not null" json:"quantity"`
+    ReorderPoint int     `gorm:"not null;default:0" json:"reorder_point"` // остаток, при котором пора дозаказать; 0 - не задан
+    Version      uint    `gorm:"not null;default:1" json:"version"` // увеличивается при каждом изменении, отдается как ETag; колонка - миграция 0002
 }
\ No newline at end of file
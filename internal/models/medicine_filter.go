@@ -0,0 +1,11 @@
+package models
+
+// MedicineFilter - условия отбора лекарств для списка и экспорта.
+// Пустые поля не ограничивают выборку
+type MedicineFilter struct {
+    Name     string   // подстрока названия, без учета регистра
+    Barcode  string   // точное совпадение штрихкода
+    MinPrice *float64
+    MaxPrice *float64
+    InStock  *bool    // true - только в наличии, false - только отсутствующие
+}
\ No newline at end of file
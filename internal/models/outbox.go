@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// OutboxMessage - событие, записанное в одной транзакции с изменением данных.
+// Ретранслятор (outbox.Relay) отправляет такие записи в Kafka и отмечает отправленными.
+// Таблица - миграции 0004_create_outbox и 0009_add_outbox_headers
+type OutboxMessage struct {
+    ID            uint       `gorm:"primaryKey" json:"id"`
+    Topic         string     `gorm:"not null" json:"topic"`
+    Key           string     `json:"key"`
+    Payload       string     `gorm:"type:jsonb;not null" json:"payload"`
+    Headers       string     `gorm:"type:jsonb;not null;default:'{}'" json:"headers"` // заголовки Kafka, например traceparent
+    Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+    LastError     string     `json:"last_error"`
+    NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+    SentAt        *time.Time `gorm:"index" json:"sent_at"`
+    CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (OutboxMessage) TableName() string {
+    return "outbox"
+}
\ No newline at end of file
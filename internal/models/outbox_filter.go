@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// OutboxFilter - условия отбора сообщений outbox для повторной отправки.
+// Пустые поля не ограничивают выборку
+type OutboxFilter struct {
+    IDs   []uint
+    Topic string
+    Since *time.Time // созданные не раньше
+    Until *time.Time // созданные раньше
+}
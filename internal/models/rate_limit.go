@@ -0,0 +1,68 @@
+package models
+
+import (
+    "math"
+    "time"
+)
+
+// RateLimit - лимит token bucket: корзина вмещает Burst запросов и пополняется
+// со скоростью Rate запросов в секунду
+type RateLimit struct {
+    Rate  float64
+    Burst int
+}
+
+// RateLimitBucket - корзина одного клиента. ExpiresAt - когда корзина снова заполнится:
+// после этого запись не отличается от отсутствующей и может быть удалена
+type RateLimitBucket struct {
+    Key        string    `gorm:"primaryKey" json:"key"`
+    Tokens     float64   `gorm:"not null" json:"tokens"`
+    RefilledAt time.Time `gorm:"not null" json:"refilled_at"`
+    ExpiresAt  time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// RateLimitResult - решение по запросу
+type RateLimitResult struct {
+    Allowed    bool
+    Remaining  int           // сколько запросов еще можно сделать сразу
+    RetryAfter time.Duration // через сколько появится токен; 0, если запрос разрешен
+    Reset      time.Duration // через сколько корзина заполнится полностью
+}
+
+// Window возвращает время полного пополнения пустой корзины
+func (l RateLimit) Window() time.Duration {
+    return seconds(float64(l.Burst) / l.Rate)
+}
+
+// Take пополняет корзину за время с прошлого запроса и забирает из нее один токен.
+// Новая корзина (нулевой RefilledAt) считается полной
+func (l RateLimit) Take(bucket *RateLimitBucket, now time.Time) RateLimitResult {
+    burst := float64(l.Burst)
+    tokens := burst
+    if !bucket.RefilledAt.IsZero() {
+        elapsed := math.Max(now.Sub(bucket.RefilledAt).Seconds(), 0)
+        tokens = math.Min(burst, bucket.Tokens+elapsed*l.Rate)
+    }
+
+    result := RateLimitResult{Allowed: tokens >= 1}
+    if result.Allowed {
+        tokens--
+    } else {
+        result.RetryAfter = seconds((1 - tokens) / l.Rate)
+    }
+    result.Remaining = int(tokens)
+    result.Reset = seconds((burst - tokens) / l.Rate)
+
+    bucket.Tokens = tokens
+    bucket.RefilledAt = now
+    bucket.ExpiresAt = now.Add(result.Reset)
+    return result
+}
+
+func seconds(s float64) time.Duration {
+    return time.Duration(s * float64(time.Second))
+}
+
+func (RateLimitBucket) TableName() string {
+    return "rate_limit_buckets"
+}
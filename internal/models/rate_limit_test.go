@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimitTake(t *testing.T) {
+	limit := RateLimit{Rate: 1, Burst: 3} // 3 запроса сразу, затем 1 в секунду
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		after time.Duration // время запроса от start
+		want  RateLimitResult
+	}{
+		{name: "new bucket is full", after: 0, want: RateLimitResult{Allowed: true, Remaining: 2, Reset: time.Second}},
+		{name: "second", after: 0, want: RateLimitResult{Allowed: true, Remaining: 1, Reset: 2 * time.Second}},
+		{name: "last token", after: 0, want: RateLimitResult{Allowed: true, Remaining: 0, Reset: 3 * time.Second}},
+		{name: "empty", after: 0, want: RateLimitResult{Allowed: false, Remaining: 0, RetryAfter: time.Second, Reset: 3 * time.Second}},
+		{name: "partly refilled", after: 500 * time.Millisecond, want: RateLimitResult{Allowed: false, Remaining: 0, RetryAfter: 500 * time.Millisecond, Reset: 2500 * time.Millisecond}},
+		{name: "refilled one token", after: 1500 * time.Millisecond, want: RateLimitResult{Allowed: true, Remaining: 0, Reset: 2500 * time.Millisecond}},
+		{name: "refill is capped at burst", after: time.Hour, want: RateLimitResult{Allowed: true, Remaining: 2, Reset: time.Second}},
+		{name: "clock going back adds nothing", after: time.Hour - time.Minute, want: RateLimitResult{Allowed: true, Remaining: 1, Reset: 2 * time.Second}},
+	}
+
+	var bucket RateLimitBucket
+	for _, tt := range tests {
+		now := start.Add(tt.after)
+		got := limit.Take(&bucket, now)
+		if got != tt.want {
+			t.Errorf("%s: Take = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if want := now.Add(tt.want.Reset); !bucket.ExpiresAt.Equal(want) {
+			t.Errorf("%s: ExpiresAt = %v, want %v", tt.name, bucket.ExpiresAt, want)
+		}
+	}
+}
+
+func TestRateLimitWindow(t *testing.T) {
+	if got := (RateLimit{Rate: 0.5, Burst: 30}).Window(); got != time.Minute {
+		t.Errorf("Window = %v, want 1m", got)
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// SigningKey - ключ подписи JWT. Новые токены подписываются активным ключом (RetiredAt = nil),
+// его идентификатор передается в заголовке kid. Выведенный из оборота ключ еще принимается,
+// пока не истекут подписанные им токены
+type SigningKey struct {
+    KID       string     `gorm:"primaryKey" json:"kid"`
+    Secret    []byte     `gorm:"not null" json:"-"`
+    CreatedAt time.Time  `json:"created_at"`
+    RetiredAt *time.Time `gorm:"index" json:"retired_at"`
+}
@@ -2,8 +2,15 @@ package models
 
 import "gorm.io/gorm"
 
+// Роли пользователей
+const (
+    RoleUser  = "user"
+    RoleAdmin = "admin"
+)
+
 type User struct {
     gorm.Model
     Username string `gorm:"uniqueIndex;not null" json:"username"`
     Password string `gorm:"not null" json:"password"`
+    Role     string `gorm:"not null;default:user" json:"role"`
 }
\ No newline at end of file
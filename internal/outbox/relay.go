@@ -0,0 +1,190 @@
+// Package outbox публикует события, записанные в таблицу outbox
+// в одной транзакции с изменением данных (transactional outbox)
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"pharmacy-api/internal/events"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+	"pharmacy-api/internal/tracing"
+)
+
+// Config - параметры ретранслятора
+type Config struct {
+	PollInterval time.Duration // пауза между опросами, когда отправлять нечего
+	BatchSize    int           // сколько сообщений забирать за один опрос
+	MinBackoff   time.Duration // задержка перед первой повторной попыткой
+	MaxBackoff   time.Duration // максимальная задержка между попытками
+	SendTimeout  time.Duration // сколько ждать подтверждения одного сообщения
+}
+
+// DefaultConfig - параметры по умолчанию
+var DefaultConfig = Config{
+	PollInterval: time.Second,
+	BatchSize:    100,
+	MinBackoff:   time.Second,
+	MaxBackoff:   5 * time.Minute,
+	SendTimeout:  10 * time.Second,
+}
+
+// Relay забирает неотправленные сообщения outbox, отправляет их и отмечает отправленными.
+// Сообщение отмечается только после подтверждения брокера, поэтому доставка - at-least-once:
+// при сбое между отправкой и отметкой сообщение уйдет повторно
+type Relay struct {
+	transactor repositories.Transactor
+	publisher  events.Publisher
+	cfg        Config
+}
+
+// NewRelay создает новый экземпляр Relay
+func NewRelay(transactor repositories.Transactor, publisher events.Publisher, cfg Config) *Relay {
+	return &Relay{transactor: transactor, publisher: publisher, cfg: cfg}
+}
+
+// Run отправляет сообщения, пока не будет отменен ctx
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		sent, err := r.RelayBatch(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Outbox relay failed", "error", err)
+		}
+
+		// Полная пачка - вероятно, есть еще сообщения, забираем сразу
+		wait := r.cfg.PollInterval
+		if err == nil && sent == r.cfg.BatchSize {
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Outbox relay stopped")
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RelayBatch отправляет одну пачку сообщений и возвращает количество отправленных.
+// Пачка забирается короткой транзакцией (SKIP LOCKED и аренда на время отправки), поэтому
+// несколько реплик не отправляют одно сообщение одновременно, а транзакция не ждет брокера.
+// На первой ошибке пачка прерывается: сообщение откладывается с экспоненциальной задержкой,
+// остальные сообщения с тем же ключом - вместе с ним, чтобы события одного лекарства не обогнали
+// неотправленное, прочие возвращаются в очередь
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	messages, err := r.claim(ctx)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	var sent []uint
+	var failed *models.OutboxMessage
+	var sendErr error
+	for i := range messages {
+		if sendErr = r.send(ctx, messages[i]); sendErr != nil {
+			failed = &messages[i]
+			slog.WarnContext(ctx, "Failed to send outbox message", "outbox_id", failed.ID, "topic", failed.Topic, "attempt", failed.Attempts+1, "error", sendErr)
+			break
+		}
+		sent = append(sent, messages[i].ID)
+	}
+
+	// Результат записывается и при остановке: отправленные сообщения не должны уйти повторно
+	ctx = context.WithoutCancel(ctx)
+	err = r.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		now := time.Now()
+		for _, id := range sent {
+			if err := tx.Outbox().MarkSent(ctx, id, now); err != nil {
+				return err
+			}
+		}
+		if failed == nil {
+			return nil
+		}
+		retryAt := now.Add(r.backoff(*failed))
+		if err := tx.Outbox().MarkFailed(ctx, failed.ID, sendErr.Error(), retryAt); err != nil {
+			return err
+		}
+		var held, rest []uint
+		for _, message := range messages[len(sent)+1:] {
+			if sameKey(message, *failed) {
+				held = append(held, message.ID)
+			} else {
+				rest = append(rest, message.ID)
+			}
+		}
+		if err := tx.Outbox().Lease(ctx, held, retryAt); err != nil {
+			return err
+		}
+		return tx.Outbox().Lease(ctx, rest, now)
+	})
+	return len(sent), err
+}
+
+// sameKey сообщает, что сообщения должны уйти в Kafka в порядке записи (одна партиция).
+// Сообщения без ключа не упорядочены
+func sameKey(a, b models.OutboxMessage) bool {
+	return a.Key != "" && a.Topic == b.Topic && a.Key == b.Key
+}
+
+// claim забирает пачку сообщений и арендует их на время, за которое отправка всей пачки
+// гарантированно закончится. Если ретранслятор упадет, сообщения отправит следующий опрос после аренды
+func (r *Relay) claim(ctx context.Context) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := r.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		now := time.Now()
+		var err error
+		messages, err = tx.Outbox().FetchPending(ctx, r.cfg.BatchSize, now)
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+		ids := make([]uint, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+		lease := time.Duration(len(messages)+1) * r.cfg.SendTimeout
+		return tx.Outbox().Lease(ctx, ids, now.Add(lease))
+	})
+	return messages, err
+}
+
+// send отправляет сообщение, ожидая подтверждения не дольше SendTimeout
+func (r *Relay) send(ctx context.Context, message models.OutboxMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.SendTimeout)
+	defer cancel()
+
+	headers := make(map[string]string)
+	if message.Headers != "" {
+		if err := json.Unmarshal([]byte(message.Headers), &headers); err != nil {
+			slog.WarnContext(ctx, "Ignoring invalid outbox message headers", "outbox_id", message.ID, "error", err)
+			headers = make(map[string]string)
+		}
+	}
+	// В outbox хранятся события CloudEvents в структурированном режиме
+	headers["content-type"] = events.ContentType
+	// Публикация продолжает трассу запроса, записавшего событие
+	ctx = tracing.Extract(ctx, headers)
+
+	return r.publisher.Publish(ctx, events.Message{
+		Topic:   message.Topic,
+		Key:     message.Key,
+		Value:   []byte(message.Payload),
+		Headers: headers,
+	})
+}
+
+// backoff возвращает задержку перед следующей попыткой: MinBackoff * 2^attempts, не больше MaxBackoff
+func (r *Relay) backoff(message models.OutboxMessage) time.Duration {
+	delay := r.cfg.MinBackoff
+	for i := 0; i < message.Attempts && delay < r.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.cfg.MaxBackoff {
+		delay = r.cfg.MaxBackoff
+	}
+	return delay
+}
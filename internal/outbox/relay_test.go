@@ -0,0 +1,219 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"pharmacy-api/internal/events"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+)
+
+// outboxStore - таблица outbox в памяти. FetchPending не пропускает ключи с более ранним
+// неотправленным сообщением, поэтому порядок проверяется на самом ретрансляторе
+type outboxStore struct {
+	repositories.OutboxRepository
+	messages map[uint]*models.OutboxMessage
+}
+
+func newOutboxStore(messages ...models.OutboxMessage) *outboxStore {
+	s := &outboxStore{messages: make(map[uint]*models.OutboxMessage)}
+	for i := range messages {
+		message := messages[i]
+		s.messages[message.ID] = &message
+	}
+	return s
+}
+
+func (s *outboxStore) FetchPending(ctx context.Context, limit int, now time.Time) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	for _, message := range s.messages {
+		if message.SentAt == nil && !message.NextAttemptAt.After(now) {
+			messages = append(messages, *message)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (s *outboxStore) Lease(ctx context.Context, ids []uint, until time.Time) error {
+	for _, id := range ids {
+		s.messages[id].NextAttemptAt = until
+	}
+	return nil
+}
+
+func (s *outboxStore) MarkSent(ctx context.Context, id uint, sentAt time.Time) error {
+	s.messages[id].SentAt = &sentAt
+	return nil
+}
+
+func (s *outboxStore) MarkFailed(ctx context.Context, id uint, lastError string, nextAttemptAt time.Time) error {
+	s.messages[id].Attempts++
+	s.messages[id].LastError = lastError
+	s.messages[id].NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+type tx struct {
+	repositories.Tx
+	outbox *outboxStore
+}
+
+func (t tx) Outbox() repositories.OutboxRepository { return t.outbox }
+
+type transactor struct{ outbox *outboxStore }
+
+func (t transactor) Transaction(ctx context.Context, fn func(tx repositories.Tx) error) error {
+	return fn(tx{outbox: t.outbox})
+}
+
+// publisher запоминает отправленные ключи и отказывает на сообщениях из fail
+type publisher struct {
+	fail map[string]bool
+	sent []string
+}
+
+func (p *publisher) Publish(ctx context.Context, messages ...events.Message) error {
+	for _, message := range messages {
+		if p.fail[string(message.Value)] {
+			return errors.New("broker unavailable")
+		}
+		p.sent = append(p.sent, string(message.Value))
+	}
+	return nil
+}
+
+func (p *publisher) Close() error { return nil }
+
+func message(id uint, key, payload string) models.OutboxMessage {
+	return models.OutboxMessage{ID: id, Topic: "medicines", Key: key, Payload: payload}
+}
+
+var testConfig = Config{BatchSize: 10, MinBackoff: time.Minute, MaxBackoff: time.Hour, SendTimeout: time.Second}
+
+func TestRelayBatch(t *testing.T) {
+	store := newOutboxStore(
+		message(1, "1", "a1"),
+		message(2, "2", "b1"),
+		message(3, "1", "a2"),
+		message(4, "", "c1"),
+	)
+	pub := &publisher{}
+	relay := NewRelay(transactor{store}, pub, testConfig)
+
+	sent, err := relay.RelayBatch(context.Background())
+	if err != nil {
+		t.Fatalf("RelayBatch: %v", err)
+	}
+	if sent != 4 {
+		t.Errorf("sent = %d, want 4", sent)
+	}
+	if want := []string{"a1", "b1", "a2", "c1"}; !reflect.DeepEqual(pub.sent, want) {
+		t.Errorf("published %v, want %v", pub.sent, want)
+	}
+	for id, message := range store.messages {
+		if message.SentAt == nil {
+			t.Errorf("message %d not marked sent", id)
+		}
+	}
+}
+
+func TestRelayBatchKeepsKeyOrderOnFailure(t *testing.T) {
+	store := newOutboxStore(
+		message(1, "2", "b1"),
+		message(2, "1", "a1"),
+		message(3, "2", "b2"),
+		message(4, "1", "a2"),
+		message(5, "", "c1"),
+	)
+	pub := &publisher{fail: map[string]bool{"a1": true}}
+	relay := NewRelay(transactor{store}, pub, testConfig)
+
+	start := time.Now()
+	sent, err := relay.RelayBatch(context.Background())
+	if err != nil {
+		t.Fatalf("RelayBatch: %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("sent = %d, want 1", sent)
+	}
+
+	failed := store.messages[2]
+	if failed.Attempts != 1 || failed.LastError != "broker unavailable" {
+		t.Errorf("failed message attempts = %d, last_error = %q", failed.Attempts, failed.LastError)
+	}
+	if failed.NextAttemptAt.Before(start.Add(testConfig.MinBackoff)) {
+		t.Errorf("failed message next attempt %v, want after backoff", failed.NextAttemptAt)
+	}
+	// Следующее сообщение того же ключа ждет вместе с неотправленным
+	if held := store.messages[4]; !held.NextAttemptAt.Equal(failed.NextAttemptAt) {
+		t.Errorf("message with failed key next attempt %v, want %v", held.NextAttemptAt, failed.NextAttemptAt)
+	}
+	// Сообщения других ключей и без ключа сразу возвращаются в очередь
+	for _, id := range []uint{3, 5} {
+		if next := store.messages[id].NextAttemptAt; next.After(time.Now()) {
+			t.Errorf("message %d next attempt %v, want released now", id, next)
+		}
+	}
+
+	pub.fail = nil
+	if _, err := relay.RelayBatch(context.Background()); err != nil {
+		t.Fatalf("RelayBatch: %v", err)
+	}
+	if want := []string{"b1", "b2", "c1"}; !reflect.DeepEqual(pub.sent, want) {
+		t.Errorf("published %v, want %v", pub.sent, want)
+	}
+}
+
+func TestRelayBatchRetry(t *testing.T) {
+	store := newOutboxStore(message(1, "1", "a1"), message(2, "1", "a2"))
+	pub := &publisher{fail: map[string]bool{"a1": true}}
+	cfg := testConfig
+	cfg.MinBackoff = 0
+	relay := NewRelay(transactor{store}, pub, cfg)
+
+	if _, err := relay.RelayBatch(context.Background()); err != nil {
+		t.Fatalf("RelayBatch: %v", err)
+	}
+	pub.fail = nil
+	sent, err := relay.RelayBatch(context.Background())
+	if err != nil {
+		t.Fatalf("RelayBatch: %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("sent = %d, want 2", sent)
+	}
+	if want := []string{"a1", "a2"}; !reflect.DeepEqual(pub.sent, want) {
+		t.Errorf("published %v, want %v", pub.sent, want)
+	}
+	if attempts := store.messages[1].Attempts; attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	relay := NewRelay(nil, nil, Config{MinBackoff: time.Second, MaxBackoff: time.Minute})
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: time.Second},
+		{attempts: 1, want: 2 * time.Second},
+		{attempts: 5, want: 32 * time.Second},
+		{attempts: 6, want: time.Minute},
+		{attempts: 100, want: time.Minute},
+	}
+	for _, tt := range tests {
+		if got := relay.backoff(models.OutboxMessage{Attempts: tt.attempts}); got != tt.want {
+			t.Errorf("backoff(attempts=%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -1,17 +1,119 @@
 package repositories
 
-import "pharmacy-api/internal/models"
+import (
+    "context"
+    "errors"
+    "time"
+
+    "pharmacy-api/internal/models"
+)
+
+var (
+    // ErrNotFound - запись не найдена
+    ErrNotFound = errors.New("record not found")
+    // ErrDuplicate - нарушено ограничение уникальности
+    ErrDuplicate = errors.New("duplicate record")
+    // ErrVersionConflict - запись изменена другим запросом (версия не совпала)
+    ErrVersionConflict = errors.New("version conflict")
+)
 
 type UserRepository interface {
-    Create(user *models.User) error
-    GetByUsername(username string) (*models.User, error)
-    GetByID(id uint) (*models.User, error)
+    Create(ctx context.Context, user *models.User) error
+    GetByUsername(ctx context.Context, username string) (*models.User, error)
+    GetByID(ctx context.Context, id uint) (*models.User, error)
+    UpdatePassword(ctx context.Context, id uint, password string) error
+    UpdateRole(ctx context.Context, id uint, role string) error
 }
 
 type MedicineRepository interface {
-    Create(medicine models.Medicine) (models.Medicine, error)
-    GetByID(id int) (models.Medicine, error)
-    GetAll() ([]models.Medicine, error)
-    Update(id int, medicine models.Medicine) (models.Medicine, error)
-    Delete(id int) error
+    Create(ctx context.Context, medicine models.Medicine) (models.Medicine, error)
+    GetByID(ctx context.Context, id int) (models.Medicine, error)
+    GetByBarcode(ctx context.Context, barcode string) (models.Medicine, error)
+    GetByName(ctx context.Context, name string) (models.Medicine, error)
+    GetAll(ctx context.Context, filter models.MedicineFilter) ([]models.Medicine, error)
+    Stream(ctx context.Context, filter models.MedicineFilter, fn func(models.Medicine) error) error
+    Update(ctx context.Context, id int, medicine models.Medicine, version uint) (models.Medicine, error)
+    // AddStock атомарно увеличивает остаток лекарства на quantity
+    AddStock(ctx context.Context, id int, quantity int) (models.Medicine, error)
+    Delete(ctx context.Context, id int, version uint) error
+    GetDeleted(ctx context.Context) ([]models.Medicine, error)
+    GetDeletedByID(ctx context.Context, id int) (models.Medicine, error)
+    Restore(ctx context.Context, id int) (models.Medicine, error)
+    Purge(ctx context.Context, id int) error
+    // PurgeDeletedBefore навсегда удаляет лекарства из корзины старше cutoff и возвращает удаленные
+    PurgeDeletedBefore(ctx context.Context, cutoff time.Time) ([]models.Medicine, error)
+    InventoryStats(ctx context.Context) (models.InventoryStats, error)
+}
+
+// OutboxRepository хранит исходящие события (transactional outbox)
+type OutboxRepository interface {
+    Add(ctx context.Context, message *models.OutboxMessage) error
+    // FetchPending блокирует (FOR UPDATE SKIP LOCKED) до limit неотправленных сообщений,
+    // время повторной попытки которых наступило. Сообщение не выбирается, пока не отправлено более
+    // раннее сообщение того же топика с тем же ключом. Вызывается внутри транзакции
+    FetchPending(ctx context.Context, limit int, now time.Time) ([]models.OutboxMessage, error)
+    // Lease переносит следующую попытку сообщений на until: забранные ретранслятором сообщения
+    // не видны другим репликам, пока идет отправка, и возвращаются в очередь, если он упал
+    Lease(ctx context.Context, ids []uint, until time.Time) error
+    MarkSent(ctx context.Context, id uint, sentAt time.Time) error
+    MarkFailed(ctx context.Context, id uint, lastError string, nextAttemptAt time.Time) error
+    // Requeue снова ставит в очередь сообщения под filter, в том числе уже отправленные
+    Requeue(ctx context.Context, filter models.OutboxFilter, now time.Time) (int64, error)
+    // CountPending возвращает количество неотправленных сообщений
+    CountPending(ctx context.Context) (int64, error)
+}
+
+// DeliveryRepository хранит учтенные поставки, приходы и несопоставленные строки поставок
+type DeliveryRepository interface {
+    // MarkProcessed отмечает поставку учтенной; ErrDuplicate, если она уже учтена
+    MarkProcessed(ctx context.Context, delivery *models.ProcessedDelivery) error
+    AddReceipt(ctx context.Context, receipt *models.StockReceipt) error
+    AddUnmatched(ctx context.Context, item *models.UnmatchedDeliveryItem) error
+    // GetUnmatched возвращает несопоставленные строки, ожидающие решения
+    GetUnmatched(ctx context.Context) ([]models.UnmatchedDeliveryItem, error)
+    // GetUnmatchedForUpdate блокирует строку, ожидающую решения; ErrNotFound, если ее нет или она уже сопоставлена
+    GetUnmatchedForUpdate(ctx context.Context, id int) (models.UnmatchedDeliveryItem, error)
+    Resolve(ctx context.Context, id int, medicineID uint, resolvedAt time.Time) error
+}
+
+// IdempotencyStore хранит ответы на запросы с Idempotency-Key
+type IdempotencyStore interface {
+    // Reserve занимает ключ для нового запроса. Если ключ уже занят и не истек,
+    // возвращает существующую запись и ErrDuplicate
+    Reserve(ctx context.Context, key *models.IdempotencyKey) (*models.IdempotencyKey, error)
+    // Complete сохраняет ответ на запрос
+    Complete(ctx context.Context, key string, statusCode int, header string, body []byte) error
+    // Release освобождает ключ, если ответ сохранять не нужно (ошибка сервера)
+    Release(ctx context.Context, key string) error
+    DeleteExpired(ctx context.Context, now time.Time) (int64, error)
 }
+
+// RateLimitStore хранит корзины token bucket. Общий для реплик store (postgres) делает лимиты
+// общими для всех экземпляров; в памяти у каждого экземпляра свои корзины
+type RateLimitStore interface {
+    // Take забирает токен из корзины key по лимиту limit
+    Take(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.RateLimitResult, error)
+    // DeleteExpired удаляет корзины, которые уже заполнились
+    DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// SigningKeyRepository хранит ключи подписи JWT
+type SigningKeyRepository interface {
+    // List возвращает все ключи, от новых к старым
+    List(ctx context.Context) ([]models.SigningKey, error)
+    // Rotate выводит из оборота активные ключи и добавляет key в одной транзакции
+    Rotate(ctx context.Context, key *models.SigningKey, now time.Time) error
+    DeleteRetiredBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// Tx предоставляет репозитории, работающие в рамках одной транзакции
+type Tx interface {
+    Medicines() MedicineRepository
+    Outbox() OutboxRepository
+    Deliveries() DeliveryRepository
+}
+
+// Transactor выполняет fn в транзакции: коммит, если fn вернула nil, иначе откат
+type Transactor interface {
+    Transaction(ctx context.Context, fn func(tx Tx) error) error
+}
\ No newline at end of file
@@ -0,0 +1,72 @@
+// Package memory - реализации репозиториев в памяти процесса (один экземпляр приложения, тесты)
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+)
+
+// IdempotencyStore хранит ключи идемпотентности в памяти
+type IdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]models.IdempotencyKey
+}
+
+// NewIdempotencyStore создает новый экземпляр IdempotencyStore
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{keys: make(map[string]models.IdempotencyKey)}
+}
+
+// Reserve занимает ключ; истекший ключ заменяется
+func (s *IdempotencyStore) Reserve(ctx context.Context, key *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.keys[key.Key]; ok && existing.ExpiresAt.After(time.Now()) {
+		return &existing, repositories.ErrDuplicate
+	}
+	key.CreatedAt = time.Now()
+	s.keys[key.Key] = *key
+	return nil, nil
+}
+
+// Complete сохраняет ответ на запрос
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, statusCode int, header string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.keys[key]
+	if !ok {
+		return repositories.ErrNotFound
+	}
+	record.StatusCode, record.Header, record.Body = statusCode, header, body
+	s.keys[key] = record
+	return nil
+}
+
+// Release освобождает ключ
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, key)
+	return nil
+}
+
+// DeleteExpired удаляет истекшие ключи
+func (s *IdempotencyStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for k, record := range s.keys {
+		if !record.ExpiresAt.After(now) {
+			delete(s.keys, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
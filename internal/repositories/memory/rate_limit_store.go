@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"pharmacy-api/internal/models"
+)
+
+// RateLimitStore хранит корзины token bucket в памяти; у каждого экземпляра приложения свои лимиты
+type RateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]models.RateLimitBucket
+}
+
+// NewRateLimitStore создает новый экземпляр RateLimitStore
+func NewRateLimitStore() *RateLimitStore {
+	return &RateLimitStore{buckets: make(map[string]models.RateLimitBucket)}
+}
+
+// Take забирает токен из корзины key
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket := s.buckets[key]
+	result := limit.Take(&bucket, now)
+	bucket.Key = key
+	s.buckets[key] = bucket
+	return result, nil
+}
+
+// DeleteExpired удаляет заполнившиеся корзины
+func (s *RateLimitStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for key, bucket := range s.buckets {
+		if !bucket.ExpiresAt.After(now) {
+			delete(s.buckets, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// deliveryRepository implements the DeliveryRepository interface
+type deliveryRepository struct {
+	db *gorm.DB
+}
+
+// NewDeliveryRepository creates a new instance of DeliveryRepository
+func NewDeliveryRepository(db *gorm.DB) repositories.DeliveryRepository {
+	return &deliveryRepository{db: db}
+}
+
+// MarkProcessed inserts the delivery; the primary key rejects a delivery processed before
+func (r *deliveryRepository) MarkProcessed(ctx context.Context, delivery *models.ProcessedDelivery) error {
+	return translateError(r.db.WithContext(ctx).Create(delivery).Error)
+}
+
+// AddReceipt stores a stock receipt
+func (r *deliveryRepository) AddReceipt(ctx context.Context, receipt *models.StockReceipt) error {
+	return r.db.WithContext(ctx).Create(receipt).Error
+}
+
+// AddUnmatched parks a delivery item for manual review
+func (r *deliveryRepository) AddUnmatched(ctx context.Context, item *models.UnmatchedDeliveryItem) error {
+	return r.db.WithContext(ctx).Create(item).Error
+}
+
+// GetUnmatched retrieves unresolved items, oldest first
+func (r *deliveryRepository) GetUnmatched(ctx context.Context) ([]models.UnmatchedDeliveryItem, error) {
+	var items []models.UnmatchedDeliveryItem
+	result := r.db.WithContext(ctx).Where("resolved_at IS NULL").Order("id").Find(&items)
+	return items, result.Error
+}
+
+// GetUnmatchedForUpdate locks an unresolved item until the end of the transaction
+func (r *deliveryRepository) GetUnmatchedForUpdate(ctx context.Context, id int) (models.UnmatchedDeliveryItem, error) {
+	var item models.UnmatchedDeliveryItem
+	result := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("resolved_at IS NULL").
+		First(&item, id)
+	if result.Error != nil {
+		return models.UnmatchedDeliveryItem{}, translateError(result.Error)
+	}
+	return item, nil
+}
+
+// Resolve marks an item as matched with a medicine
+func (r *deliveryRepository) Resolve(ctx context.Context, id int, medicineID uint, resolvedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.UnmatchedDeliveryItem{}).
+		Where("id = ? AND resolved_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"medicine_id": medicineID,
+			"resolved_at": resolvedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
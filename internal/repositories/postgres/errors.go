@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"errors"
+
+	"pharmacy-api/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// translateError maps gorm errors onto the repository-level sentinel errors,
+// so the layers above don't depend on gorm
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return repositories.ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return repositories.ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// idempotencyStore implements the IdempotencyStore interface
+type idempotencyStore struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyStore creates a new instance of IdempotencyStore
+func NewIdempotencyStore(db *gorm.DB) repositories.IdempotencyStore {
+	return &idempotencyStore{db: db}
+}
+
+// Reserve inserts the key unless an unexpired one exists; an expired key is replaced
+func (s *idempotencyStore) Reserve(ctx context.Context, key *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	// header is JSONB: an empty string is not valid JSON and fails the insert
+	if key.Header == "" {
+		key.Header = "{}"
+	}
+	var existing models.IdempotencyKey
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("key = ? AND expires_at <= ?", key.Key, time.Now()).Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+		if err := tx.Where("key = ?", key.Key).First(&existing).Error; err != nil {
+			return translateError(err)
+		}
+		return repositories.ErrDuplicate
+	})
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return &existing, err
+	}
+	return nil, err
+}
+
+// Complete stores the response of the request
+func (s *idempotencyStore) Complete(ctx context.Context, key string, statusCode int, header string, body []byte) error {
+	return s.db.WithContext(ctx).Model(&models.IdempotencyKey{}).Where("key = ?", key).Updates(map[string]interface{}{
+		"status_code": statusCode,
+		"header":      header,
+		"body":        body,
+	}).Error
+}
+
+// Release deletes a key whose response must not be replayed
+func (s *idempotencyStore) Release(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("key = ?", key).Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpired deletes keys whose TTL has passed
+func (s *idempotencyStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
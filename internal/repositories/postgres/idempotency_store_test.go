@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	store := NewIdempotencyStore(testDB(t, "idempotency_keys"))
+	ctx := context.Background()
+	newKey := func(hash string, expiresAt time.Time) *models.IdempotencyKey {
+		return &models.IdempotencyKey{Key: "1:k1", RequestHash: hash, ExpiresAt: expiresAt}
+	}
+	hour := time.Now().Add(time.Hour)
+
+	if _, err := store.Reserve(ctx, newKey("h1", hour)); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+
+	// Повтор во время выполнения: ответа еще нет
+	existing, err := store.Reserve(ctx, newKey("h2", hour))
+	if !errors.Is(err, repositories.ErrDuplicate) {
+		t.Fatalf("Reserve duplicate: error = %v, want ErrDuplicate", err)
+	}
+	if existing.RequestHash != "h1" || existing.StatusCode != 0 {
+		t.Errorf("in-progress key = %+v", existing)
+	}
+
+	// Повтор после выполнения получает сохраненный ответ
+	if err := store.Complete(ctx, "1:k1", 201, `{"Location":"/medicines/1"}`, []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	existing, err = store.Reserve(ctx, newKey("h1", hour))
+	if !errors.Is(err, repositories.ErrDuplicate) {
+		t.Fatalf("Reserve replay: error = %v, want ErrDuplicate", err)
+	}
+	if existing.StatusCode != 201 || string(existing.Body) != `{"id":1}` || existing.Header != `{"Location": "/medicines/1"}` {
+		t.Errorf("completed key = status %d, header %s, body %s", existing.StatusCode, existing.Header, existing.Body)
+	}
+
+	// Освобожденный ключ можно занять снова
+	if err := store.Release(ctx, "1:k1"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, err := store.Reserve(ctx, newKey("h2", time.Now().Add(-time.Second))); err != nil {
+		t.Fatalf("Reserve after release: %v", err)
+	}
+
+	// Истекший ключ заменяется новым запросом и удаляется очисткой
+	if _, err := store.Reserve(ctx, newKey("h3", time.Now().Add(-time.Second))); err != nil {
+		t.Fatalf("Reserve over expired key: %v", err)
+	}
+	deleted, err := store.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("DeleteExpired: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeleteExpired = %d, want 1", deleted)
+	}
+}
@@ -1,9 +1,14 @@
 package postgres
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"pharmacy-api/internal/models"
 	"pharmacy-api/internal/repositories" // Import the repositories package
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // medicineRepository implements the MedicineRepository interface
@@ -17,46 +22,223 @@ func NewMedicineRepository(db *gorm.DB) repositories.MedicineRepository { // Imp
 }
 
 // Create creates a new medicine
-func (r *medicineRepository) Create(medicine models.Medicine) (models.Medicine, error) {
-	result := r.db.Create(&medicine)
+func (r *medicineRepository) Create(ctx context.Context, medicine models.Medicine) (models.Medicine, error) {
+	result := r.db.WithContext(ctx).Create(&medicine)
 	if result.Error != nil {
-		return models.Medicine{}, result.Error // Return empty Medicine struct on error
+		return models.Medicine{}, translateError(result.Error) // Return empty Medicine struct on error
 	}
 	return medicine, nil
 }
 
 // GetByID retrieves a medicine by ID
-func (r *medicineRepository) GetByID(id int) (models.Medicine, error) { // Changed id type
+func (r *medicineRepository) GetByID(ctx context.Context, id int) (models.Medicine, error) { // Changed id type
+	var medicine models.Medicine
+	result := r.db.WithContext(ctx).First(&medicine, id)
+	if result.Error != nil {
+		return models.Medicine{}, translateError(result.Error) // Return empty Medicine struct on error
+	}
+	return medicine, nil
+}
+
+// GetByBarcode retrieves a medicine by barcode
+func (r *medicineRepository) GetByBarcode(ctx context.Context, barcode string) (models.Medicine, error) {
+	var medicine models.Medicine
+	result := r.db.WithContext(ctx).Where("barcode = ?", barcode).First(&medicine)
+	if result.Error != nil {
+		return models.Medicine{}, translateError(result.Error)
+	}
+	return medicine, nil
+}
+
+// GetByName retrieves a medicine by name (case-insensitive)
+func (r *medicineRepository) GetByName(ctx context.Context, name string) (models.Medicine, error) {
 	var medicine models.Medicine
-	result := r.db.First(&medicine, id)
+	result := r.db.WithContext(ctx).Where("LOWER(name) = LOWER(?)", name).First(&medicine)
 	if result.Error != nil {
-		return models.Medicine{}, result.Error // Return empty Medicine struct on error
+		return models.Medicine{}, translateError(result.Error)
 	}
 	return medicine, nil
 }
 
-// GetAll retrieves all medicines
-func (r *medicineRepository) GetAll() ([]models.Medicine, error) {
+// GetAll retrieves all medicines matching the filter
+func (r *medicineRepository) GetAll(ctx context.Context, filter models.MedicineFilter) ([]models.Medicine, error) {
+	var medicines []models.Medicine
+	result := applyMedicineFilter(r.db.WithContext(ctx), filter).Find(&medicines)
+	return medicines, result.Error
+}
+
+// Stream calls fn for every medicine matching the filter, reading rows one by one
+// instead of loading the whole result set into memory
+func (r *medicineRepository) Stream(ctx context.Context, filter models.MedicineFilter, fn func(models.Medicine) error) error {
+	rows, err := applyMedicineFilter(r.db.WithContext(ctx).Model(&models.Medicine{}), filter).Order("id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var medicine models.Medicine
+		if err := r.db.WithContext(ctx).ScanRows(rows, &medicine); err != nil {
+			return err
+		}
+		if err := fn(medicine); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// applyMedicineFilter adds WHERE conditions for the non-empty filter fields
+func applyMedicineFilter(db *gorm.DB, filter models.MedicineFilter) *gorm.DB {
+	if filter.Name != "" {
+		db = db.Where("name ILIKE ?", "%"+escapeLike(filter.Name)+"%")
+	}
+	if filter.Barcode != "" {
+		db = db.Where("barcode = ?", filter.Barcode)
+	}
+	if filter.MinPrice != nil {
+		db = db.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		db = db.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			db = db.Where("quantity > 0")
+		} else {
+			db = db.Where("quantity <= 0")
+		}
+	}
+	return db
+}
+
+// escapeLike escapes LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Update replaces all mutable fields of a medicine if its current version equals version.
+// The check is part of the UPDATE statement, so concurrent writers cannot both succeed
+func (r *medicineRepository) Update(ctx context.Context, id int, medicine models.Medicine, version uint) (models.Medicine, error) {
+	result := r.db.WithContext(ctx).Model(&models.Medicine{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(map[string]interface{}{
+			"name":          medicine.Name,
+			"description":   medicine.Description,
+			"barcode":       medicine.Barcode,
+			"price":         medicine.Price,
+			"quantity":      medicine.Quantity,
+			"reorder_point": medicine.ReorderPoint,
+			"version":       gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return models.Medicine{}, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.Medicine{}, r.versionMismatchError(ctx, id)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// AddStock increments the quantity in a single UPDATE, so concurrent receipts are not lost
+func (r *medicineRepository) AddStock(ctx context.Context, id int, quantity int) (models.Medicine, error) {
+	result := r.db.WithContext(ctx).Model(&models.Medicine{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"quantity": gorm.Expr("quantity + ?", quantity),
+			"version":  gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return models.Medicine{}, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.Medicine{}, repositories.ErrNotFound
+	}
+	return r.GetByID(ctx, id)
+}
+
+// Delete soft-deletes a medicine by ID if its current version equals version
+func (r *medicineRepository) Delete(ctx context.Context, id int, version uint) error {
+	result := r.db.WithContext(ctx).Where("version = ?", version).Delete(&models.Medicine{}, id)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return r.versionMismatchError(ctx, id)
+	}
+	return nil
+}
+
+// versionMismatchError tells apart a missing record from a stale version
+func (r *medicineRepository) versionMismatchError(ctx context.Context, id int) error {
+	if _, err := r.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return repositories.ErrVersionConflict
+}
+
+// GetDeleted retrieves soft-deleted medicines, most recently deleted first
+func (r *medicineRepository) GetDeleted(ctx context.Context) ([]models.Medicine, error) {
 	var medicines []models.Medicine
-	result := r.db.Find(&medicines)
+	result := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&medicines)
 	return medicines, result.Error
 }
 
-// Update updates an existing medicine
-func (r *medicineRepository) Update(id int, medicine models.Medicine) (models.Medicine, error) {
-	var existingMedicine models.Medicine
-	result := r.db.First(&existingMedicine, id)
+// GetDeletedByID retrieves a soft-deleted medicine by ID
+func (r *medicineRepository) GetDeletedByID(ctx context.Context, id int) (models.Medicine, error) {
+	var medicine models.Medicine
+	result := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&medicine, id)
+	if result.Error != nil {
+		return models.Medicine{}, translateError(result.Error)
+	}
+	return medicine, nil
+}
+
+// Restore clears DeletedAt of a soft-deleted medicine
+func (r *medicineRepository) Restore(ctx context.Context, id int) (models.Medicine, error) {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Medicine{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
 	if result.Error != nil {
 		return models.Medicine{}, result.Error
 	}
-	existingMedicine.Name = medicine.Name
-	existingMedicine.Price = medicine.Price // Предполагаю, что есть поле Price
-	result = r.db.Save(&existingMedicine)
-	return existingMedicine, result.Error
+	if result.RowsAffected == 0 {
+		return models.Medicine{}, repositories.ErrNotFound
+	}
+	return r.GetByID(ctx, id)
 }
 
-// Delete deletes a medicine by ID
-func (r *medicineRepository) Delete(id int) error { //Changed id type
-	result := r.db.Delete(&models.Medicine{}, id)
-	return result.Error
-}
\ No newline at end of file
+// Purge permanently deletes a medicine that is already in the trash
+func (r *medicineRepository) Purge(ctx context.Context, id int) error {
+	result := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").Delete(&models.Medicine{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedBefore permanently deletes medicines soft-deleted before cutoff and returns them (DELETE ... RETURNING)
+func (r *medicineRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) ([]models.Medicine, error) {
+	var purged []models.Medicine
+	result := r.db.WithContext(ctx).Unscoped().Clauses(clause.Returning{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Delete(&purged)
+	return purged, result.Error
+}
+
+// InventoryStats aggregates the catalog in a single query; soft-deleted medicines are excluded
+func (r *medicineRepository) InventoryStats(ctx context.Context) (models.InventoryStats, error) {
+	var stats models.InventoryStats
+	err := r.db.WithContext(ctx).Model(&models.Medicine{}).
+		Select(`COUNT(*) AS medicines,
+			COALESCE(SUM(price * quantity), 0) AS stock_value,
+			COUNT(*) FILTER (WHERE reorder_point > 0 AND quantity <= reorder_point) AS below_reorder_point`).
+		Scan(&stats).Error
+	return stats, err
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// outboxRepository implements the OutboxRepository interface
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new instance of OutboxRepository
+func NewOutboxRepository(db *gorm.DB) repositories.OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Add stores a new message, ready to be sent immediately
+func (r *outboxRepository) Add(ctx context.Context, message *models.OutboxMessage) error {
+	if message.NextAttemptAt.IsZero() {
+		message.NextAttemptAt = time.Now()
+	}
+	if message.Headers == "" {
+		message.Headers = "{}"
+	}
+	return r.db.WithContext(ctx).Create(message).Error
+}
+
+// FetchPending locks due unsent messages; rows locked by another relay are skipped.
+// A message waits while an older message with the same topic and key is unsent, so a failed
+// event is never overtaken by later events of the same aggregate
+func (r *outboxRepository) FetchPending(ctx context.Context, limit int, now time.Time) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	result := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("sent_at IS NULL AND next_attempt_at <= ?", now).
+		Where(`COALESCE(key, '') = '' OR NOT EXISTS (
+			SELECT 1 FROM outbox AS older
+			WHERE older.topic = outbox.topic AND older.key = outbox.key AND older.sent_at IS NULL AND older.id < outbox.id)`).
+		Order("id").
+		Limit(limit).
+		Find(&messages)
+	return messages, result.Error
+}
+
+// Lease postpones the next attempt of the messages without counting it as a failure
+func (r *outboxRepository) Lease(ctx context.Context, ids []uint, until time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).Where("id IN ?", ids).Update("next_attempt_at", until).Error
+}
+
+// MarkSent records a successful delivery
+func (r *outboxRepository) MarkSent(ctx context.Context, id uint, sentAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).Where("id = ?", id).Update("sent_at", sentAt).Error
+}
+
+// MarkFailed records a failed attempt and schedules the next one
+func (r *outboxRepository) MarkFailed(ctx context.Context, id uint, lastError string, nextAttemptAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      lastError,
+		"next_attempt_at": nextAttemptAt,
+	}).Error
+}
+
+// Requeue resets matching messages so the relay sends them again on its next poll
+func (r *outboxRepository) Requeue(ctx context.Context, filter models.OutboxFilter, now time.Time) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.OutboxMessage{})
+	if len(filter.IDs) > 0 {
+		query = query.Where("id IN ?", filter.IDs)
+	}
+	if filter.Topic != "" {
+		query = query.Where("topic = ?", filter.Topic)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+	result := query.Session(&gorm.Session{AllowGlobalUpdate: true}).Updates(map[string]interface{}{
+		"sent_at":         nil,
+		"attempts":        0,
+		"last_error":      "",
+		"next_attempt_at": now,
+	})
+	return result.RowsAffected, result.Error
+}
+
+// CountPending counts messages that have not been sent yet
+func (r *outboxRepository) CountPending(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.OutboxMessage{}).Where("sent_at IS NULL").Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pharmacy-api/internal/models"
+)
+
+func TestOutboxFetchPendingKeepsKeyOrder(t *testing.T) {
+	repo := NewOutboxRepository(testDB(t, "outbox"))
+	ctx := context.Background()
+	now := time.Now()
+
+	add := func(key string, nextAttemptAt time.Time) uint {
+		message := &models.OutboxMessage{Topic: "medicines", Key: key, Payload: "{}", NextAttemptAt: nextAttemptAt}
+		if err := repo.Add(ctx, message); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		return message.ID
+	}
+	failed := add("1", now.Add(time.Minute)) // отложено после ошибки отправки
+	add("1", now)
+	other := add("2", now)
+	unkeyed := add("", now)
+
+	messages, err := repo.FetchPending(ctx, 10, now)
+	if err != nil {
+		t.Fatalf("FetchPending: %v", err)
+	}
+	var ids []uint
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	if len(ids) != 2 || ids[0] != other || ids[1] != unkeyed {
+		t.Errorf("FetchPending = %v, want [%d %d]", ids, other, unkeyed)
+	}
+
+	if err := repo.MarkSent(ctx, failed, now); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+	messages, err = repo.FetchPending(ctx, 10, now)
+	if err != nil {
+		t.Fatalf("FetchPending: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Errorf("FetchPending after the older message is sent returned %d messages, want 3", len(messages))
+	}
+}
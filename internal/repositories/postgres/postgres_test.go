@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"pharmacy-api/internal/migrations"
+	dbpkg "pharmacy-api/pkg/database/postgres"
+
+	"gorm.io/gorm"
+)
+
+// testDB подключается к базе из TEST_DATABASE_URL и применяет миграции; без переменной тест пропускается.
+// Таблицы tables очищаются до и после теста, поэтому базу не стоит делить с приложением
+func testDB(t *testing.T, tables ...string) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	opts := dbpkg.DefaultOptions
+	opts.ConnectTimeout = 0
+	db, err := dbpkg.NewPostgresDB(ctx, dsn, opts)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	truncate := func() {
+		for _, table := range tables {
+			if err := db.Exec("TRUNCATE " + table).Error; err != nil {
+				t.Fatalf("truncate %s: %v", table, err)
+			}
+		}
+	}
+	truncate()
+	t.Cleanup(func() {
+		truncate()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rateLimitStore implements the RateLimitStore interface; buckets are shared by all replicas
+type rateLimitStore struct {
+	db *gorm.DB
+}
+
+// NewRateLimitStore creates a new instance of RateLimitStore
+func NewRateLimitStore(db *gorm.DB) repositories.RateLimitStore {
+	return &rateLimitStore{db: db}
+}
+
+// Take updates the bucket under a row lock, so concurrent requests of a client are counted one by one
+func (s *rateLimitStore) Take(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.RateLimitResult, error) {
+	var result models.RateLimitResult
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A missing bucket is created full, so the first requests of a client never race on INSERT
+		full := models.RateLimitBucket{Key: key, Tokens: float64(limit.Burst), RefilledAt: now, ExpiresAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&full).Error; err != nil {
+			return err
+		}
+		var bucket models.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&bucket).Error; err != nil {
+			return translateError(err)
+		}
+		result = limit.Take(&bucket, now)
+		return tx.Save(&bucket).Error
+	})
+	return result, err
+}
+
+// DeleteExpired deletes buckets that have refilled completely
+func (s *rateLimitStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.RateLimitBucket{})
+	return result.RowsAffected, result.Error
+}
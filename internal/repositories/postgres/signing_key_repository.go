@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// signingKeyRepository implements the SigningKeyRepository interface
+type signingKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewSigningKeyRepository creates a new instance of SigningKeyRepository
+func NewSigningKeyRepository(db *gorm.DB) repositories.SigningKeyRepository {
+	return &signingKeyRepository{db: db}
+}
+
+// List returns all keys, newest first
+func (r *signingKeyRepository) List(ctx context.Context) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	err := r.db.WithContext(ctx).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Rotate retires the active keys and stores the new one atomically
+func (r *signingKeyRepository) Rotate(ctx context.Context, key *models.SigningKey, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SigningKey{}).Where("retired_at IS NULL").Update("retired_at", now).Error; err != nil {
+			return err
+		}
+		return translateError(tx.Create(key).Error)
+	})
+}
+
+// DeleteRetiredBefore removes keys retired before cutoff
+func (r *signingKeyRepository) DeleteRetiredBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("retired_at < ?", cutoff).Delete(&models.SigningKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+
+	"pharmacy-api/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// transactor implements the Transactor interface on top of gorm transactions
+type transactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor creates a new instance of Transactor
+func NewTransactor(db *gorm.DB) repositories.Transactor {
+	return &transactor{db: db}
+}
+
+// Transaction runs fn inside a single database transaction; cancelling ctx rolls it back
+func (t *transactor) Transaction(ctx context.Context, fn func(tx repositories.Tx) error) error {
+	return t.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		return fn(&txRepositories{db: db})
+	})
+}
+
+// txRepositories hands out repositories bound to the transaction
+type txRepositories struct {
+	db *gorm.DB
+}
+
+func (r *txRepositories) Medicines() repositories.MedicineRepository {
+	return NewMedicineRepository(r.db)
+}
+
+func (r *txRepositories) Outbox() repositories.OutboxRepository {
+	return NewOutboxRepository(r.db)
+}
+
+func (r *txRepositories) Deliveries() repositories.DeliveryRepository {
+	return NewDeliveryRepository(r.db)
+}
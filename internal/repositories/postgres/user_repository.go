@@ -1,7 +1,9 @@
 package postgres
 
 import (
+	"context"
 	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
 	"gorm.io/gorm"
 )
 
@@ -13,24 +15,44 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	return translateError(r.db.WithContext(ctx).Create(user).Error)
 }
 
-func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("username = ?", username).First(&user).Error
+	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	return &user, nil
 }
 
-func (r *UserRepository) GetByID(id uint) (*models.User, error) {
+func (r *UserRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
     var user models.User
-    err := r.db.First(&user, id).Error
+    err := r.db.WithContext(ctx).First(&user, id).Error
     if err != nil {
-        return nil, err
+        return nil, translateError(err)
     }
     return &user, nil
-}
\ No newline at end of file
+}
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, id uint, password string) error {
+	return r.update(ctx, id, "password", password)
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	return r.update(ctx, id, "role", role)
+}
+
+// update changes a single column; ErrNotFound if the user doesn't exist
+func (r *UserRepository) update(ctx context.Context, id uint, column string, value interface{}) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update(column, value)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+)
+
+// CreateUser создает пользователя с ролью role и публикует событие регистрации
+func (s *AuthService) CreateUser(ctx context.Context, username, password, role string) (*models.User, error) {
+	if err := validateCredentials(username, password); err != nil {
+		return nil, err
+	}
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username: username,
+		Password: string(hashedPassword),
+		Role:     role,
+	}
+
+	err = s.userRepo.Create(ctx, user)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return nil, &ConflictError{Message: fmt.Sprintf("username %q is already taken", username)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishRegistrationEvent(ctx, user)
+	return user, nil
+}
+
+// ResetPassword задает пользователю новый пароль
+func (s *AuthService) ResetPassword(ctx context.Context, username, password string) error {
+	if err := validateCredentials(username, password); err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return notFoundOr(err, "user", username)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return notFoundOr(s.userRepo.UpdatePassword(ctx, user.ID, string(hashedPassword)), "user", username)
+}
+
+// AssignRole меняет роль пользователя. Уже выданные токены сохраняют прежнюю роль до истечения
+func (s *AuthService) AssignRole(ctx context.Context, username, role string) error {
+	if err := validateRole(role); err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return notFoundOr(err, "user", username)
+	}
+	return notFoundOr(s.userRepo.UpdateRole(ctx, user.ID, role), "user", username)
+}
+
+// validateCredentials проверяет имя пользователя и пароль
+func validateCredentials(username, password string) error {
+	var errs []FieldError
+	if username == "" {
+		errs = append(errs, FieldError{Field: "username", Message: "is required"})
+	}
+	if password == "" {
+		errs = append(errs, FieldError{Field: "password", Message: "is required"})
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+// validateRole проверяет, что роль известна
+func validateRole(role string) error {
+	switch role {
+	case models.RoleUser, models.RoleAdmin:
+		return nil
+	}
+	return &ValidationError{Fields: []FieldError{{Field: "role", Message: fmt.Sprintf("must be %s or %s", models.RoleUser, models.RoleAdmin)}}}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"pharmacy-api/internal/events"
+	"pharmacy-api/internal/models"
+	"time"
+)
+
+// eventPublishTimeout ограничивает, насколько недоступный брокер может задержать вход или регистрацию
+const eventPublishTimeout = 5 * time.Second
+
+// publishLoginEvent публикует событие входа; ошибка публикации не мешает входу и только логируется
+func (s *AuthService) publishLoginEvent(ctx context.Context, username string, success bool, description string) {
+	s.publishEvent(ctx, s.cfg.LoginTopic, events.UserLogin, username, events.UserLoginV1{
+		Username:    username,
+		Success:     success,
+		Description: description,
+	})
+}
+
+// publishRegistrationEvent публикует событие регистрации; ошибка публикации только логируется
+func (s *AuthService) publishRegistrationEvent(ctx context.Context, user *models.User) {
+	s.publishEvent(ctx, s.cfg.RegistrationTopic, events.UserRegistered, user.Username, events.UserRegisteredV1{
+		UserID:   user.ID,
+		Username: user.Username,
+	})
+}
+
+// publishEvent оборачивает данные в CloudEvents и публикует их; subject и ключ сообщения - имя пользователя
+func (s *AuthService) publishEvent(ctx context.Context, topic, eventType, username string, data interface{}) {
+	if topic == "" {
+		return
+	}
+
+	envelope, err := events.NewEnvelope(eventType, username, data)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to build event", "event_type", eventType, "error", err)
+		return
+	}
+	envelope.CorrelationID = CorrelationIDFromContext(ctx)
+	message, err := envelope.Message(topic, username)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal event", "event_type", eventType, "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, eventPublishTimeout)
+	defer cancel()
+	if err := s.publisher.Publish(ctx, message); err != nil {
+		slog.ErrorContext(ctx, "Failed to publish event", "topic", topic, "event_type", eventType, "error", err)
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+)
+
+const (
+	// signingKeysRefresh - как часто перечитываются ключи: за это время все реплики
+	// начинают подписывать токены ключом, созданным ротацией на другом экземпляре
+	signingKeysRefresh = time.Minute
+	// signingKeysMinReload - не чаще этого ключи перечитываются из-за неизвестного kid
+	signingKeysMinReload = 5 * time.Second
+	// signingKeySize - размер секрета HS256 в байтах
+	signingKeySize = 32
+)
+
+// signingKeys - кеш ключей подписи JWT из SigningKeyRepository.
+// Пока ключей нет (или репозиторий не задан), используется JWTSecret из настроек без kid
+type signingKeys struct {
+	repo     repositories.SigningKeyRepository
+	fallback []byte
+	tokenTTL time.Duration
+
+	mu       sync.RWMutex
+	keys     map[string]models.SigningKey
+	active   *models.SigningKey
+	oldest   time.Time // создание самого старого ключа; нулевое - ключей нет
+	loadedAt time.Time
+}
+
+// signingKey возвращает ключ для подписи нового токена: kid пустой для JWTSecret
+func (k *signingKeys) signingKey(ctx context.Context) (string, []byte, error) {
+	if err := k.refresh(ctx, false); err != nil {
+		return "", nil, err
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.active != nil {
+		return k.active.KID, k.active.Secret, nil
+	}
+	return "", k.fallback, nil
+}
+
+// verificationKey возвращает ключ для проверки токена с заголовком kid
+func (k *signingKeys) verificationKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Header["alg"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// JWTSecret принимается, пока не истекли токены, выданные до первой ротации
+		if err := k.refresh(ctx, false); err != nil {
+			return nil, err
+		}
+		k.mu.RLock()
+		oldest := k.oldest
+		k.mu.RUnlock()
+		if !oldest.IsZero() && time.Since(oldest) > k.tokenTTL {
+			return nil, errors.New("tokens without kid are no longer accepted")
+		}
+		return k.fallback, nil
+	}
+
+	key, ok := k.lookup(ctx, kid)
+	if !ok {
+		// Ключ мог быть создан ротацией на другом экземпляре
+		if err := k.refresh(ctx, true); err != nil {
+			return nil, err
+		}
+		if key, ok = k.lookup(ctx, kid); !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+	if key.RetiredAt != nil && time.Since(*key.RetiredAt) > k.tokenTTL {
+		return nil, fmt.Errorf("signing key %q is retired", kid)
+	}
+	return key.Secret, nil
+}
+
+// lookup ищет ключ по kid в кеше
+func (k *signingKeys) lookup(ctx context.Context, kid string) (models.SigningKey, bool) {
+	if err := k.refresh(ctx, false); err != nil {
+		slog.ErrorContext(ctx, "Failed to load signing keys", "error", err)
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// refresh перечитывает ключи, если кеш устарел; force - перечитать раньше срока (не чаще signingKeysMinReload)
+func (k *signingKeys) refresh(ctx context.Context, force bool) error {
+	if k.repo == nil {
+		return nil
+	}
+	k.mu.RLock()
+	age := time.Since(k.loadedAt)
+	k.mu.RUnlock()
+	if age < signingKeysRefresh && (!force || age < signingKeysMinReload) {
+		return nil
+	}
+	return k.reload(ctx)
+}
+
+// reload перечитывает ключи из репозитория
+func (k *signingKeys) reload(ctx context.Context) error {
+	list, err := k.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]models.SigningKey, len(list))
+	var active *models.SigningKey
+	var oldest time.Time
+	for i, key := range list {
+		keys[key.KID] = key
+		if key.RetiredAt == nil && active == nil {
+			active = &list[i]
+		}
+		if oldest.IsZero() || key.CreatedAt.Before(oldest) {
+			oldest = key.CreatedAt
+		}
+	}
+
+	k.mu.Lock()
+	k.keys, k.active, k.oldest, k.loadedAt = keys, active, oldest, time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+// RotateSigningKey создает новый ключ подписи и выводит из оборота текущий.
+// Токены, подписанные прежним ключом, принимаются до истечения их срока (TokenTTL);
+// ключи, выведенные раньше, удаляются
+func (s *AuthService) RotateSigningKey(ctx context.Context) (models.SigningKey, error) {
+	if s.keys.repo == nil {
+		return models.SigningKey{}, errors.New("signing key storage is not configured")
+	}
+
+	secret := make([]byte, signingKeySize)
+	if _, err := rand.Read(secret); err != nil {
+		return models.SigningKey{}, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return models.SigningKey{}, err
+	}
+
+	now := time.Now()
+	key := models.SigningKey{KID: hex.EncodeToString(id), Secret: secret, CreatedAt: now}
+	if err := s.keys.repo.Rotate(ctx, &key, now); err != nil {
+		return models.SigningKey{}, err
+	}
+	if _, err := s.keys.repo.DeleteRetiredBefore(ctx, now.Add(-s.cfg.TokenTTL)); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired signing keys", "error", err)
+	}
+	if err := s.keys.reload(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to reload signing keys", "error", err)
+	}
+	return key, nil
+}
+
+// SigningKeys возвращает ключи подписи, от новых к старым
+func (s *AuthService) SigningKeys(ctx context.Context) ([]models.SigningKey, error) {
+	if s.keys.repo == nil {
+		return nil, nil
+	}
+	return s.keys.repo.List(ctx)
+}
@@ -3,117 +3,96 @@ package services
 import (
 	"context"
 	"errors"
-	"fmt"
-	"log"
-	"os"
+	"pharmacy-api/internal/events"
 	"pharmacy-api/internal/models"
 	"pharmacy-api/internal/repositories"
-	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
-	"github.com/segmentio/kafka-go"
 	"golang.org/x/crypto/bcrypt"
 )
 
-type AuthService struct {
-	userRepo    repositories.UserRepository
-	jwtSecret   string
-	kafkaWriter *kafka.Writer
-	
+// AuthConfig - настройки AuthService
+type AuthConfig struct {
+	JWTSecret         string
+	TokenTTL          time.Duration
+	LoginTopic        string // топик событий входа; пустой - события не публикуются
+	RegistrationTopic string // топик событий регистрации; пустой - события не публикуются
 }
 
+type AuthService struct {
+	userRepo  repositories.UserRepository
+	keys      *signingKeys
+	publisher events.Publisher
+	cfg       AuthConfig
+}
 
-
-func NewAuthService(userRepo repositories.UserRepository) *AuthService {
-	brokersString := os.Getenv("KAFKA_BROKERS")
-	if brokersString == "" {
-		log.Fatalf("Error: KAFKA_BROKERS environment variable not set or empty")
-	}
-	brokers := strings.Split(brokersString, ",")
-	log.Printf("Kafka Brokers: %v", brokers)
-
-	topic := os.Getenv("KAFKA_TOPIC")
-	if topic == "" {
-		log.Fatalf("Error: KAFKA_TOPIC environment variable not set")
-	}
-
-
-	// Создаем Kafka writer
-	kafkaWriter := &kafka.Writer{
-		Addr:      kafka.TCP(brokers...), // Используем Addr вместо Brokers
-		Topic:     topic,
-		Balancer:  &kafka.LeastBytes{},
-	}
-
+// NewAuthService создает новый экземпляр AuthService.
+// signingKeyRepo может быть nil - тогда токены подписываются только JWTSecret
+func NewAuthService(userRepo repositories.UserRepository, signingKeyRepo repositories.SigningKeyRepository, publisher events.Publisher, cfg AuthConfig) *AuthService {
 	return &AuthService{
-		userRepo:    userRepo,
-		jwtSecret:   os.Getenv("JWT_SECRET"),
-		kafkaWriter: kafkaWriter,
+		userRepo: userRepo,
+		keys: &signingKeys{
+			repo:     signingKeyRepo,
+			fallback: []byte(cfg.JWTSecret),
+			tokenTTL: cfg.TokenTTL,
+		},
+		publisher: publisher,
+		cfg:       cfg,
 	}
 }
 
-func (s *AuthService) Register(username, password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
-	}
-
-	user := &models.User{
-		Username: username,
-		Password: string(hashedPassword),
-	}
-
-	err = s.userRepo.Create(user)
-	if err != nil {
-		return err
-	}
-
-	// Отправка сообщения в Kafka после успешной регистрации
-	err = s.SendRegistrationMessage(context.Background(), username)
-	if err != nil {
-		log.Printf("Failed to send registration message: %v", err)
-	}
-
-	return nil
+func (s *AuthService) Register(ctx context.Context, username, password string) error {
+	_, err := s.CreateUser(ctx, username, password, models.RoleUser)
+	return err
 }
 
-func (s *AuthService) Login(username, password string) (string, error) {
-	user, err := s.userRepo.GetByUsername(username)
-	if err != nil {
-		return "", errors.New("invalid credentials")
+func (s *AuthService) Login(ctx context.Context, username, password string) (string, error) {
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if errors.Is(err, repositories.ErrNotFound) || (err == nil && user == nil) {
+		s.publishLoginEvent(ctx, username, false, "Invalid credentials")
+		return "", ErrInvalidCredentials
 	}
-
-	if user == nil {
-		return "", errors.New("invalid credentials")
+	if err != nil {
+		return "", err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return "", errors.New("invalid credentials")
+		s.publishLoginEvent(ctx, username, false, "Invalid credentials")
+		return "", ErrInvalidCredentials
 	}
 
-	token, err := s.generateJWT(user)
+	token, err := s.generateJWT(ctx, user)
 	if err != nil {
 		return "", err
 	}
 
+	s.publishLoginEvent(ctx, username, true, "Login successful")
 	return token, nil
 }
 
-func (s *AuthService) generateJWT(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(1 * time.Hour)
+func (s *AuthService) generateJWT(ctx context.Context, user *models.User) (string, error) {
+	expirationTime := time.Now().Add(s.cfg.TokenTTL)
 	claims := &Claims{
 		UserID:   user.ID,
 		Username: user.Username,
+		Role:     user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	kid, secret, err := s.keys.signingKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
 
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	tokenString, err := token.SignedString(secret)
 	if err != nil {
 		return "", err
 	}
@@ -124,12 +103,13 @@ func (s *AuthService) generateJWT(user *models.User) (string, error) {
 type Claims struct {
 	UserID   uint
 	Username string
+	Role     string
 	jwt.RegisteredClaims
 }
 
-func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
+func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.jwtSecret), nil
+		return s.keys.verificationKey(ctx, token)
 	})
 
 	if err != nil {
@@ -143,23 +123,3 @@ func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
-
-func (s *AuthService) SendRegistrationMessage(ctx context.Context, username string) error {
-	message := kafka.Message{
-		Key:   []byte("registration"),
-		Value: []byte(fmt.Sprintf("New user registered: %s", username)),
-		Time:  time.Now(),
-	}
-
-	if s.kafkaWriter == nil {
-		log.Println("Kafka writer is not initialized")
-		return errors.New("kafka writer is not initialized")
-	}
-
-	err := s.kafkaWriter.WriteMessages(ctx, message)
-	if err != nil {
-		return fmt.Errorf("failed to write registration message: %w", err)
-	}
-	log.Printf("Sent registration message: %s\n", string(message.Value))
-	return nil
-}
\ No newline at end of file
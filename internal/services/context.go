@@ -0,0 +1,32 @@
+package services
+
+import "context"
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	correlationIDKey
+)
+
+// WithActor возвращает контекст с ID пользователя, от имени которого выполняется действие
+func WithActor(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, actorKey, userID)
+}
+
+// ActorFromContext возвращает ID пользователя из контекста; 0 - действие системы (фоновые задачи)
+func ActorFromContext(ctx context.Context) uint {
+	userID, _ := ctx.Value(actorKey).(uint)
+	return userID
+}
+
+// WithCorrelationID возвращает контекст с ID цепочки запросов
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, correlationID)
+}
+
+// CorrelationIDFromContext возвращает ID цепочки запросов из контекста
+func CorrelationIDFromContext(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey).(string)
+	return correlationID
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"pharmacy-api/internal/events"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+	"strings"
+	"time"
+)
+
+// DeliveryReceivedEvent - тип события складской системы о поступлении поставки
+const DeliveryReceivedEvent = "warehouse.delivery.received.v1"
+
+// Delivery - накладная поставки от складской системы
+type Delivery struct {
+	DeliveryID string         `json:"delivery_id"`
+	Supplier   string         `json:"supplier"`
+	Items      []DeliveryItem `json:"items"`
+}
+
+// DeliveryItem - строка накладной
+type DeliveryItem struct {
+	Barcode  string `json:"barcode"`
+	Lot      string `json:"lot"`
+	Expiry   string `json:"expiry"` // срок годности, YYYY-MM-DD
+	Quantity int    `json:"quantity"`
+}
+
+// DeliveryService учитывает поставки в остатках лекарств
+type DeliveryService interface {
+	ProcessDelivery(ctx context.Context, delivery Delivery) error
+	GetUnmatchedItems(ctx context.Context) ([]models.UnmatchedDeliveryItem, error)
+	ResolveUnmatchedItem(ctx context.Context, id int, medicineID int) (models.StockReceipt, error)
+}
+
+type deliveryService struct {
+	deliveryRepository repositories.DeliveryRepository
+	transactor         repositories.Transactor
+	eventTopic         string // топик событий лекарств; пустой - события не пишутся
+}
+
+// NewDeliveryService создает новый экземпляр DeliveryService
+func NewDeliveryService(deliveryRepository repositories.DeliveryRepository, transactor repositories.Transactor, eventTopic string) DeliveryService {
+	return &deliveryService{
+		deliveryRepository: deliveryRepository,
+		transactor:         transactor,
+		eventTopic:         eventTopic,
+	}
+}
+
+// ProcessDelivery увеличивает остатки лекарств по строкам накладной. Строки сопоставляются
+// по штрихкоду; несопоставленные откладываются для ручного разбора. Вся поставка учитывается
+// в одной транзакции, повторно полученная поставка (тот же delivery_id) пропускается
+func (s *deliveryService) ProcessDelivery(ctx context.Context, delivery Delivery) error {
+	expiries, err := validateDelivery(delivery)
+	if err != nil {
+		return err
+	}
+
+	err = s.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		// Запись о поставке первой: параллельная обработка той же поставки упрется в первичный ключ
+		err := tx.Deliveries().MarkProcessed(ctx, &models.ProcessedDelivery{
+			DeliveryID:  delivery.DeliveryID,
+			Supplier:    delivery.Supplier,
+			ItemCount:   len(delivery.Items),
+			ProcessedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, item := range delivery.Items {
+			medicine, err := tx.Medicines().GetByBarcode(ctx, item.Barcode)
+			if errors.Is(err, repositories.ErrNotFound) {
+				err = tx.Deliveries().AddUnmatched(ctx, &models.UnmatchedDeliveryItem{
+					DeliveryID: delivery.DeliveryID,
+					Supplier:   delivery.Supplier,
+					Barcode:    item.Barcode,
+					Lot:        item.Lot,
+					ExpiryDate: expiries[i],
+					Quantity:   item.Quantity,
+				})
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			receipt := models.StockReceipt{
+				DeliveryID: delivery.DeliveryID,
+				Supplier:   delivery.Supplier,
+				Barcode:    item.Barcode,
+				Lot:        item.Lot,
+				ExpiryDate: expiries[i],
+				Quantity:   item.Quantity,
+			}
+			if err := s.receive(ctx, tx, medicine, &receipt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, repositories.ErrDuplicate) {
+		slog.InfoContext(ctx, "Delivery already processed, skipping", "delivery_id", delivery.DeliveryID)
+		return nil
+	}
+	return err
+}
+
+// GetUnmatchedItems возвращает строки поставок, ожидающие ручного сопоставления
+func (s *deliveryService) GetUnmatchedItems(ctx context.Context) ([]models.UnmatchedDeliveryItem, error) {
+	return s.deliveryRepository.GetUnmatched(ctx)
+}
+
+// ResolveUnmatchedItem сопоставляет отложенную строку поставки с лекарством и учитывает приход
+func (s *deliveryService) ResolveUnmatchedItem(ctx context.Context, id int, medicineID int) (models.StockReceipt, error) {
+	var receipt models.StockReceipt
+	err := s.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		item, err := tx.Deliveries().GetUnmatchedForUpdate(ctx, id)
+		if err != nil {
+			return notFoundOr(err, "unmatched delivery item", id)
+		}
+		medicine, err := tx.Medicines().GetByID(ctx, medicineID)
+		if err != nil {
+			return notFoundOr(err, "medicine", medicineID)
+		}
+
+		receipt = models.StockReceipt{
+			DeliveryID: item.DeliveryID,
+			Supplier:   item.Supplier,
+			Barcode:    item.Barcode,
+			Lot:        item.Lot,
+			ExpiryDate: item.ExpiryDate,
+			Quantity:   item.Quantity,
+		}
+		if err := s.receive(ctx, tx, medicine, &receipt); err != nil {
+			return err
+		}
+		return tx.Deliveries().Resolve(ctx, id, medicine.ID, time.Now())
+	})
+	if err != nil {
+		return models.StockReceipt{}, err
+	}
+	return receipt, nil
+}
+
+// receive увеличивает остаток лекарства, записывает приход и событие medicine.updated
+func (s *deliveryService) receive(ctx context.Context, tx repositories.Tx, medicine models.Medicine, receipt *models.StockReceipt) error {
+	updated, err := tx.Medicines().AddStock(ctx, int(medicine.ID), receipt.Quantity)
+	if err != nil {
+		return err
+	}
+	receipt.MedicineID = medicine.ID
+	if err := tx.Deliveries().AddReceipt(ctx, receipt); err != nil {
+		return err
+	}
+	return addMedicineEvent(ctx, tx, s.eventTopic, events.MedicineUpdated, &medicine, &updated)
+}
+
+// validateDelivery проверяет накладную и разбирает сроки годности строк
+func validateDelivery(delivery Delivery) ([]*time.Time, error) {
+	var errs []FieldError
+	if strings.TrimSpace(delivery.DeliveryID) == "" {
+		errs = append(errs, FieldError{Field: "delivery_id", Message: "is required"})
+	}
+	if strings.TrimSpace(delivery.Supplier) == "" {
+		errs = append(errs, FieldError{Field: "supplier", Message: "is required"})
+	}
+	if len(delivery.Items) == 0 {
+		errs = append(errs, FieldError{Field: "items", Message: "must not be empty"})
+	}
+
+	expiries := make([]*time.Time, len(delivery.Items))
+	for i, item := range delivery.Items {
+		field := fmt.Sprintf("items[%d]", i)
+		if strings.TrimSpace(item.Barcode) == "" {
+			errs = append(errs, FieldError{Field: field + ".barcode", Message: "is required"})
+		}
+		if item.Quantity <= 0 {
+			errs = append(errs, FieldError{Field: field + ".quantity", Message: "must be greater than 0"})
+		}
+		if item.Expiry != "" {
+			expiry, err := time.Parse("2006-01-02", item.Expiry)
+			if err != nil {
+				errs = append(errs, FieldError{Field: field + ".expiry", Message: "must be a date in YYYY-MM-DD format"})
+				continue
+			}
+			expiries[i] = &expiry
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+	return expiries, nil
+}
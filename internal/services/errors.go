@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"pharmacy-api/internal/repositories"
+)
+
+// Доменные ошибки сервисов. HTTP-слой сопоставляет их со статусами ответа
+// (см. middleware.ErrorHandler), поэтому обработчики просто передают ошибку дальше
+
+// NotFoundError - запрошенная сущность не существует
+type NotFoundError struct {
+	Resource string
+	ID       interface{}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %v not found", e.Resource, e.ID)
+}
+
+// ConflictError - операция противоречит текущему состоянию (например, дубликат)
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// ForbiddenError - у пользователя нет прав на операцию
+type ForbiddenError struct {
+	Message string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+// ErrInvalidCredentials - неверное имя пользователя или пароль
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// notFoundOr превращает repositories.ErrNotFound в NotFoundError, остальные ошибки возвращает как есть
+func notFoundOr(err error, resource string, id interface{}) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		return &NotFoundError{Resource: resource, ID: id}
+	}
+	return err
+}
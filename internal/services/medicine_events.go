@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"pharmacy-api/internal/catalog"
+	"pharmacy-api/internal/events"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+	"pharmacy-api/internal/tracing"
+	"strconv"
+)
+
+// enqueueMedicineEvent записывает событие в outbox в той же транзакции, что и изменение лекарства.
+// В Kafka его отправит outbox.Relay уже после коммита, поэтому событие не теряется при недоступной
+// Kafka и не появляется для откатившихся изменений.
+// before и after - состояние до и после изменения; nil, если состояния нет (создание, удаление)
+func (s *medicineService) enqueueMedicineEvent(ctx context.Context, tx repositories.Tx, eventType string, before, after *models.Medicine) error {
+	return addMedicineEvent(ctx, tx, s.eventTopic, eventType, before, after)
+}
+
+// addMedicineEvent записывает событие лекарства в outbox; пустой topic - события не пишутся.
+// Используется всеми сервисами, изменяющими лекарства
+func addMedicineEvent(ctx context.Context, tx repositories.Tx, topic, eventType string, before, after *models.Medicine) error {
+	if topic == "" {
+		return nil
+	}
+
+	data := medicineEventData(before, after)
+	id := strconv.FormatUint(uint64(data.MedicineID), 10)
+	envelope, err := events.NewEnvelope(eventType, id, data)
+	if err != nil {
+		return err
+	}
+	envelope.ActorID = ActorFromContext(ctx)
+	envelope.CorrelationID = CorrelationIDFromContext(ctx)
+
+	message, err := envelope.Message(topic, id) // события одного лекарства попадают в одну партицию
+	if err != nil {
+		return err
+	}
+
+	// Контекст трассировки запроса сохраняется, чтобы публикация продолжила его трассу
+	headers, err := json.Marshal(tracing.Headers(ctx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Outbox().Add(ctx, &models.OutboxMessage{
+		Topic:   message.Topic,
+		Key:     message.Key,
+		Payload: string(message.Value),
+		Headers: string(headers),
+	})
+}
+
+// medicineEventData собирает данные события; при наличии обоих состояний добавляет список измененных полей
+func medicineEventData(before, after *models.Medicine) events.MedicineEventV1 {
+	var data events.MedicineEventV1
+	if before != nil {
+		data.MedicineID = before.ID
+		data.Before = medicineSnapshot(*before)
+	}
+	if after != nil {
+		data.MedicineID = after.ID
+		data.After = medicineSnapshot(*after)
+	}
+	if before != nil && after != nil {
+		data.ChangedFields = changedMedicineFields(*before, *after)
+	}
+	return data
+}
+
+func medicineSnapshot(m models.Medicine) *events.MedicineV1 {
+	snapshot := &events.MedicineV1{
+		ID:           m.ID,
+		Name:         m.Name,
+		Description:  m.Description,
+		Barcode:      m.Barcode,
+		Price:        m.Price,
+		Quantity:     m.Quantity,
+		ReorderPoint: m.ReorderPoint,
+		Version:      m.Version,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+	}
+	if m.DeletedAt.Valid {
+		snapshot.DeletedAt = &m.DeletedAt.Time
+	}
+	return snapshot
+}
+
+// changedMedicineFields возвращает изменяемые поля, значения которых различаются
+func changedMedicineFields(before, after models.Medicine) []string {
+	var fields []string
+	if before.Name != after.Name {
+		fields = append(fields, catalog.FieldName)
+	}
+	if before.Description != after.Description {
+		fields = append(fields, catalog.FieldDescription)
+	}
+	if before.Barcode != after.Barcode {
+		fields = append(fields, catalog.FieldBarcode)
+	}
+	if before.Price != after.Price {
+		fields = append(fields, catalog.FieldPrice)
+	}
+	if before.Quantity != after.Quantity {
+		fields = append(fields, catalog.FieldQuantity)
+	}
+	if before.ReorderPoint != after.ReorderPoint {
+		fields = append(fields, catalog.FieldReorderPoint)
+	}
+	return fields
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pharmacy-api/internal/catalog"
+	"pharmacy-api/internal/events"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/internal/repositories"
+)
+
+// ImportOptions - параметры импорта каталога
+type ImportOptions struct {
+	DryRun bool // только посчитать изменения, ничего не записывая
+}
+
+// Действие, выполненное над строкой импорта
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+)
+
+// FieldChange - старое и новое значение поля
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// ImportChange - результат обработки одной строки файла
+type ImportChange struct {
+	Line       int                    `json:"line"`
+	Action     string                 `json:"action"`
+	MedicineID uint                   `json:"medicine_id,omitempty"`
+	Name       string                 `json:"name"`
+	Barcode    string                 `json:"barcode,omitempty"`
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+
+	before, after *models.Medicine // состояние для события об изменении
+}
+
+// ImportRowError - ошибки в одной строке файла
+type ImportRowError struct {
+	Line   int          `json:"line"`
+	Errors []FieldError `json:"errors"`
+}
+
+// ImportReport - итог импорта. Если Errors не пуст, ничего не записано
+type ImportReport struct {
+	DryRun    bool             `json:"dry_run"`
+	Total     int              `json:"total"`
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Changes   []ImportChange   `json:"changes"`
+	Errors    []ImportRowError `json:"errors,omitempty"`
+}
+
+// ImportError - в файле импорта есть ошибочные строки; отчет со всеми строками возвращается клиенту целиком
+type ImportError struct {
+	Report ImportReport
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("import failed: %d of %d rows are invalid", len(e.Report.Errors), e.Report.Total)
+}
+
+// Err возвращает ImportError с отчетом или nil, если ошибок нет
+func (r ImportReport) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return &ImportError{Report: r}
+}
+
+// errImportRollback откатывает транзакцию импорта (dry-run или ошибки в строках)
+var errImportRollback = errors.New("import rolled back")
+
+// ImportMedicines загружает строки каталога: существующие лекарства (по штрихкоду или названию)
+// обновляются, новые создаются. Все строки и события о них записываются в одной транзакции
+func (s *medicineService) ImportMedicines(ctx context.Context, records []catalog.Record, opts ImportOptions) (ImportReport, error) {
+	report := ImportReport{DryRun: opts.DryRun, Total: len(records), Changes: []ImportChange{}}
+
+	for _, rec := range records {
+		if errs := validateImportRecord(rec); len(errs) > 0 {
+			report.Errors = append(report.Errors, ImportRowError{Line: rec.Line, Errors: errs})
+		}
+	}
+	if len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	err := s.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		repo := tx.Medicines()
+		for _, rec := range records {
+			change, errs, err := importRecord(ctx, repo, rec)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", rec.Line, err)
+			}
+			if len(errs) > 0 {
+				report.Errors = append(report.Errors, ImportRowError{Line: rec.Line, Errors: errs})
+				continue
+			}
+
+			switch change.Action {
+			case ImportActionCreate:
+				report.Created++
+				if err := s.enqueueMedicineEvent(ctx, tx, events.MedicineCreated, nil, change.after); err != nil {
+					return err
+				}
+				if opts.DryRun {
+					change.MedicineID = 0 // ID из откатываемой транзакции ничего не значит
+				}
+			case ImportActionUpdate:
+				report.Updated++
+				if err := s.enqueueMedicineEvent(ctx, tx, events.MedicineUpdated, change.before, change.after); err != nil {
+					return err
+				}
+			case ImportActionUnchanged:
+				report.Unchanged++
+			}
+			report.Changes = append(report.Changes, change)
+		}
+
+		if opts.DryRun || len(report.Errors) > 0 {
+			return errImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRollback) {
+		return ImportReport{}, err
+	}
+	return report, nil
+}
+
+// validateImportRecord проверяет строку без обращения к базе
+func validateImportRecord(rec catalog.Record) []FieldError {
+	var errs []FieldError
+	for _, e := range rec.Errors {
+		errs = append(errs, FieldError{Field: e.Field, Message: e.Message})
+	}
+	if rec.Name == nil && rec.Barcode == nil {
+		errs = append(errs, FieldError{Field: catalog.FieldName, Message: "name or barcode is required"})
+	}
+	return errs
+}
+
+// importRecord создает или обновляет лекарство по строке файла
+func importRecord(ctx context.Context, repo repositories.MedicineRepository, rec catalog.Record) (ImportChange, []FieldError, error) {
+	existing, found, err := findImportTarget(ctx, repo, rec)
+	if err != nil {
+		return ImportChange{}, nil, err
+	}
+
+	if !found {
+		var errs []FieldError
+		if rec.Name == nil {
+			errs = append(errs, FieldError{Field: catalog.FieldName, Message: "is required for a new medicine"})
+		}
+		if rec.Price == nil {
+			errs = append(errs, FieldError{Field: catalog.FieldPrice, Message: "is required for a new medicine"})
+		}
+		if len(errs) > 0 {
+			return ImportChange{}, errs, nil
+		}
+
+		var medicine models.Medicine
+		applyImportRecord(&medicine, rec)
+		if errs := medicineFieldErrors(medicine); len(errs) > 0 {
+			return ImportChange{}, errs, nil
+		}
+		created, err := repo.Create(ctx, medicine)
+		if err != nil {
+			return ImportChange{}, nil, err
+		}
+		return ImportChange{
+			Line:       rec.Line,
+			Action:     ImportActionCreate,
+			MedicineID: created.ID,
+			Name:       created.Name,
+			Barcode:    created.Barcode,
+			after:      &created,
+		}, nil, nil
+	}
+
+	before := existing
+	change := ImportChange{Line: rec.Line, Action: ImportActionUnchanged, MedicineID: existing.ID}
+	change.Changes = applyImportRecord(&existing, rec)
+	if len(change.Changes) > 0 {
+		if errs := medicineFieldErrors(existing); len(errs) > 0 {
+			return ImportChange{}, errs, nil
+		}
+		change.Action = ImportActionUpdate
+		if existing, err = repo.Update(ctx, int(existing.ID), existing, existing.Version); err != nil {
+			return ImportChange{}, nil, err
+		}
+		change.before, change.after = &before, &existing
+	}
+	change.Name = existing.Name
+	change.Barcode = existing.Barcode
+	return change, nil, nil
+}
+
+// findImportTarget ищет лекарство сначала по штрихкоду, затем по названию.
+// По названию не сопоставляется лекарство с другим штрихкодом
+func findImportTarget(ctx context.Context, repo repositories.MedicineRepository, rec catalog.Record) (models.Medicine, bool, error) {
+	if rec.Barcode != nil {
+		medicine, err := repo.GetByBarcode(ctx, *rec.Barcode)
+		if err == nil {
+			return medicine, true, nil
+		}
+		if !errors.Is(err, repositories.ErrNotFound) {
+			return models.Medicine{}, false, err
+		}
+	}
+
+	if rec.Name != nil {
+		medicine, err := repo.GetByName(ctx, *rec.Name)
+		if err == nil && (rec.Barcode == nil || medicine.Barcode == "") {
+			return medicine, true, nil
+		}
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return models.Medicine{}, false, err
+		}
+	}
+	return models.Medicine{}, false, nil
+}
+
+// applyImportRecord переносит заданные в строке поля в medicine и возвращает изменения
+func applyImportRecord(medicine *models.Medicine, rec catalog.Record) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	if rec.Name != nil && *rec.Name != medicine.Name {
+		changes[catalog.FieldName] = FieldChange{From: medicine.Name, To: *rec.Name}
+		medicine.Name = *rec.Name
+	}
+	if rec.Description != nil && *rec.Description != medicine.Description {
+		changes[catalog.FieldDescription] = FieldChange{From: medicine.Description, To: *rec.Description}
+		medicine.Description = *rec.Description
+	}
+	if rec.Barcode != nil && *rec.Barcode != medicine.Barcode {
+		changes[catalog.FieldBarcode] = FieldChange{From: medicine.Barcode, To: *rec.Barcode}
+		medicine.Barcode = *rec.Barcode
+	}
+	if rec.Price != nil && *rec.Price != medicine.Price {
+		changes[catalog.FieldPrice] = FieldChange{From: medicine.Price, To: *rec.Price}
+		medicine.Price = *rec.Price
+	}
+	if rec.Quantity != nil && *rec.Quantity != medicine.Quantity {
+		changes[catalog.FieldQuantity] = FieldChange{From: medicine.Quantity, To: *rec.Quantity}
+		medicine.Quantity = *rec.Quantity
+	}
+	if rec.ReorderPoint != nil && *rec.ReorderPoint != medicine.ReorderPoint {
+		changes[catalog.FieldReorderPoint] = FieldChange{From: medicine.ReorderPoint, To: *rec.ReorderPoint}
+		medicine.ReorderPoint = *rec.ReorderPoint
+	}
+	return changes
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"pharmacy-api/internal/models"
+	"pharmacy-api/pkg/mergepatch"
+	"sort"
+)
+
+// medicineDocument - изменяемые поля лекарства, к которым применяется merge patch
+type medicineDocument struct {
+	Name         *string  `json:"name"`
+	Description  *string  `json:"description"`
+	Barcode      *string  `json:"barcode"`
+	Price        *float64 `json:"price"`
+	Quantity     *int     `json:"quantity"`
+	ReorderPoint *int     `json:"reorder_point"`
+}
+
+// patchableFields - поля, допустимые в патче; true - поле обязательное и не может быть удалено через null
+var patchableFields = map[string]bool{"name": true, "description": false, "barcode": false, "price": true, "quantity": true, "reorder_point": false}
+
+// PatchMedicine частично обновляет лекарство по JSON Merge Patch (RFC 7396):
+// изменяются только переданные поля, null очищает необязательное поле.
+// Патч применяется, только если текущая версия лекарства равна version
+func (s *medicineService) PatchMedicine(ctx context.Context, id int, patch []byte, version uint) (models.Medicine, error) {
+	if err := checkPatchFields(patch); err != nil {
+		return models.Medicine{}, err
+	}
+
+	existing, err := s.medicineRepository.GetByID(ctx, id)
+	if err != nil {
+		return models.Medicine{}, notFoundOr(err, "medicine", id)
+	}
+	if existing.Version != version {
+		return models.Medicine{}, ErrVersionMismatch
+	}
+
+	doc, err := json.Marshal(medicineDocument{
+		Name:         &existing.Name,
+		Description:  &existing.Description,
+		Barcode:      &existing.Barcode,
+		Price:        &existing.Price,
+		Quantity:     &existing.Quantity,
+		ReorderPoint: &existing.ReorderPoint,
+	})
+	if err != nil {
+		return models.Medicine{}, err
+	}
+
+	merged, err := mergepatch.Apply(doc, patch)
+	if err != nil {
+		return models.Medicine{}, &ValidationError{Fields: []FieldError{{Field: "body", Message: err.Error()}}}
+	}
+
+	var result medicineDocument
+	if err := json.Unmarshal(merged, &result); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return models.Medicine{}, &ValidationError{Fields: []FieldError{{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()}}}
+		}
+		return models.Medicine{}, err
+	}
+
+	updated := existing
+	updated.Description, updated.Barcode, updated.ReorderPoint = "", "", 0
+	if result.Name != nil {
+		updated.Name = *result.Name
+	}
+	if result.Description != nil {
+		updated.Description = *result.Description
+	}
+	if result.Barcode != nil {
+		updated.Barcode = *result.Barcode
+	}
+	if result.Price != nil {
+		updated.Price = *result.Price
+	}
+	if result.Quantity != nil {
+		updated.Quantity = *result.Quantity
+	}
+	if result.ReorderPoint != nil {
+		updated.ReorderPoint = *result.ReorderPoint
+	}
+
+	if err := validateMedicine(updated); err != nil {
+		return models.Medicine{}, err
+	}
+	return s.updateMedicine(ctx, id, updated, version, UpdateOptions{})
+}
+
+// checkPatchFields отклоняет неизвестные поля и удаление обязательных
+func checkPatchFields(patch []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		return &ValidationError{Fields: []FieldError{{Field: "body", Message: mergepatch.ErrNotObject.Error()}}}
+	}
+
+	var errs []FieldError
+	for name, value := range fields {
+		required, ok := patchableFields[name]
+		switch {
+		case !ok:
+			errs = append(errs, FieldError{Field: name, Message: "is unknown or read-only"})
+		case required && string(value) == "null":
+			errs = append(errs, FieldError{Field: name, Message: "cannot be removed"})
+		}
+	}
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
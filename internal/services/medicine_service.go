@@ -1,54 +1,202 @@
 package services
 
 import (
+	"context"
+	"pharmacy-api/internal/catalog"
+	"pharmacy-api/internal/events"
 	"pharmacy-api/internal/models"
 	"pharmacy-api/internal/repositories"
+	"time"
 )
 
+// ErrVersionMismatch - лекарство изменено с момента получения клиентом его версии
+var ErrVersionMismatch = repositories.ErrVersionConflict
+
 // MedicineService - интерфейс для сервиса medicine
 type MedicineService interface {
-	CreateMedicine(medicine models.Medicine) (models.Medicine, error)
-	GetMedicineByID(id int) (models.Medicine, error)
-	GetAllMedicines() ([]models.Medicine, error)
-	UpdateMedicine(id int, medicine models.Medicine) (models.Medicine, error)
-	DeleteMedicine(id int) error
+	CreateMedicine(ctx context.Context, medicine models.Medicine) (models.Medicine, error)
+	GetMedicineByID(ctx context.Context, id int) (models.Medicine, error)
+	GetAllMedicines(ctx context.Context, filter models.MedicineFilter) ([]models.Medicine, error)
+	StreamMedicines(ctx context.Context, filter models.MedicineFilter, fn func(models.Medicine) error) error
+	UpdateMedicine(ctx context.Context, id int, medicine models.Medicine, version uint, opts UpdateOptions) (models.Medicine, error)
+	PatchMedicine(ctx context.Context, id int, patch []byte, version uint) (models.Medicine, error)
+	DeleteMedicine(ctx context.Context, id int, version uint) error
+	ImportMedicines(ctx context.Context, records []catalog.Record, opts ImportOptions) (ImportReport, error)
+	GetDeletedMedicines(ctx context.Context) ([]models.Medicine, error)
+	RestoreMedicine(ctx context.Context, id int) (models.Medicine, error)
+	PurgeMedicine(ctx context.Context, id int) error
+	PurgeExpiredTrash(ctx context.Context, retention time.Duration) (int64, error)
 }
 
 type medicineService struct {
 	medicineRepository repositories.MedicineRepository
+	transactor         repositories.Transactor
+	eventTopic         string // топик Kafka для событий лекарств; пустой - события не пишутся
 }
 
 // NewMedicineService создает новый экземпляр MedicineService
-func NewMedicineService(medicineRepository repositories.MedicineRepository) MedicineService {
-	return &medicineService{medicineRepository: medicineRepository}
+func NewMedicineService(medicineRepository repositories.MedicineRepository, transactor repositories.Transactor, eventTopic string) MedicineService {
+	return &medicineService{
+		medicineRepository: medicineRepository,
+		transactor:         transactor,
+		eventTopic:         eventTopic,
+	}
 }
 
 // CreateMedicine создает новое лекарство
-func (s *medicineService) CreateMedicine(medicine models.Medicine) (models.Medicine, error) {
-	// Логика создания лекарства (например, валидация данных)
-	return s.medicineRepository.Create(medicine)
+func (s *medicineService) CreateMedicine(ctx context.Context, medicine models.Medicine) (models.Medicine, error) {
+	if err := validateMedicine(medicine); err != nil {
+		return models.Medicine{}, err
+	}
+
+	var created models.Medicine
+	err := s.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		var err error
+		if created, err = tx.Medicines().Create(ctx, medicine); err != nil {
+			return err
+		}
+		return s.enqueueMedicineEvent(ctx, tx, events.MedicineCreated, nil, &created)
+	})
+	if err != nil {
+		return models.Medicine{}, err
+	}
+	return created, nil
 }
 
 // GetMedicineByID возвращает лекарство по ID
-func (s *medicineService) GetMedicineByID(id int) (models.Medicine, error) {
-	// Логика получения лекарства по ID
-	return s.medicineRepository.GetByID(id)
+func (s *medicineService) GetMedicineByID(ctx context.Context, id int) (models.Medicine, error) {
+	medicine, err := s.medicineRepository.GetByID(ctx, id)
+	if err != nil {
+		return models.Medicine{}, notFoundOr(err, "medicine", id)
+	}
+	return medicine, nil
 }
 
-// GetAllMedicines возвращает все лекарства
-func (s *medicineService) GetAllMedicines() ([]models.Medicine, error) {
+// GetAllMedicines возвращает все лекарства, подходящие под фильтр
+func (s *medicineService) GetAllMedicines(ctx context.Context, filter models.MedicineFilter) ([]models.Medicine, error) {
 	// Логика получения всех лекарств
-	return s.medicineRepository.GetAll()
+	return s.medicineRepository.GetAll(ctx, filter)
+}
+
+// StreamMedicines передает в fn лекарства по одному, не загружая весь каталог в память
+func (s *medicineService) StreamMedicines(ctx context.Context, filter models.MedicineFilter, fn func(models.Medicine) error) error {
+	return s.medicineRepository.Stream(ctx, filter, fn)
+}
+
+// UpdateOptions - параметры UpdateMedicine
+type UpdateOptions struct {
+	// KeepReorderPoint сохраняет текущий reorder_point: поле добавлено позже остальных,
+	// и клиенты, которые его не передают, не должны сбрасывать его в 0
+	KeepReorderPoint bool
+}
+
+// UpdateMedicine полностью заменяет изменяемые поля лекарства, если его текущая версия равна version
+func (s *medicineService) UpdateMedicine(ctx context.Context, id int, medicine models.Medicine, version uint, opts UpdateOptions) (models.Medicine, error) {
+	if err := validateMedicine(medicine); err != nil {
+		return models.Medicine{}, err
+	}
+	return s.updateMedicine(ctx, id, medicine, version, opts)
+}
+
+// updateMedicine сохраняет лекарство и событие medicine.updated в одной транзакции.
+// Состояние до изменения читается в той же транзакции; обновление по версии гарантирует,
+// что между чтением и записью лекарство никто не изменил
+func (s *medicineService) updateMedicine(ctx context.Context, id int, medicine models.Medicine, version uint, opts UpdateOptions) (models.Medicine, error) {
+	var updated models.Medicine
+	err := s.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		before, err := tx.Medicines().GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if before.Version != version {
+			return ErrVersionMismatch
+		}
+		if opts.KeepReorderPoint {
+			medicine.ReorderPoint = before.ReorderPoint
+		}
+		if updated, err = tx.Medicines().Update(ctx, id, medicine, version); err != nil {
+			return err
+		}
+		return s.enqueueMedicineEvent(ctx, tx, events.MedicineUpdated, &before, &updated)
+	})
+	if err != nil {
+		return models.Medicine{}, notFoundOr(err, "medicine", id)
+	}
+	return updated, nil
+}
+
+// DeleteMedicine удаляет лекарство, если его текущая версия равна version
+func (s *medicineService) DeleteMedicine(ctx context.Context, id int, version uint) error {
+	err := s.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		before, err := tx.Medicines().GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if before.Version != version {
+			return ErrVersionMismatch
+		}
+		if err := tx.Medicines().Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return s.enqueueMedicineEvent(ctx, tx, events.MedicineDeleted, &before, nil)
+	})
+	return notFoundOr(err, "medicine", id)
+}
+
+// GetDeletedMedicines возвращает лекарства из корзины (мягко удаленные)
+func (s *medicineService) GetDeletedMedicines(ctx context.Context) ([]models.Medicine, error) {
+	return s.medicineRepository.GetDeleted(ctx)
+}
+
+// RestoreMedicine возвращает лекарство из корзины
+func (s *medicineService) RestoreMedicine(ctx context.Context, id int) (models.Medicine, error) {
+	var medicine models.Medicine
+	err := s.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		var err error
+		if medicine, err = tx.Medicines().Restore(ctx, id); err != nil {
+			return err
+		}
+		return s.enqueueMedicineEvent(ctx, tx, events.MedicineRestored, nil, &medicine)
+	})
+	if err != nil {
+		return models.Medicine{}, notFoundOr(err, "deleted medicine", id)
+	}
+	return medicine, nil
 }
 
-// UpdateMedicine обновляет информацию о лекарстве
-func (s *medicineService) UpdateMedicine(id int, medicine models.Medicine) (models.Medicine, error) {
-	// Логика обновления лекарства (например, валидация данных)
-	return s.medicineRepository.Update(id, medicine)
+// PurgeMedicine навсегда удаляет лекарство, которое уже находится в корзине
+func (s *medicineService) PurgeMedicine(ctx context.Context, id int) error {
+	err := s.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		before, err := tx.Medicines().GetDeletedByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Medicines().Purge(ctx, id); err != nil {
+			return err
+		}
+		return s.enqueueMedicineEvent(ctx, tx, events.MedicinePurged, &before, nil)
+	})
+	return notFoundOr(err, "deleted medicine", id)
 }
 
-// DeleteMedicine удаляет лекарство
-func (s *medicineService) DeleteMedicine(id int) error {
-	// Логика удаления лекарства
-	return s.medicineRepository.Delete(id)
+// PurgeExpiredTrash навсегда удаляет лекарства, пролежавшие в корзине дольше retention.
+// Как и при ручном удалении, о каждом лекарстве пишется событие purged в той же транзакции
+func (s *medicineService) PurgeExpiredTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	var purged []models.Medicine
+	err := s.transactor.Transaction(ctx, func(tx repositories.Tx) error {
+		var err error
+		if purged, err = tx.Medicines().PurgeDeletedBefore(ctx, time.Now().Add(-retention)); err != nil {
+			return err
+		}
+		for i := range purged {
+			if err := s.enqueueMedicineEvent(ctx, tx, events.MedicinePurged, &purged[i], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(purged)), nil
 }
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RunTrashPurger раз в interval удаляет навсегда лекарства, находящиеся в корзине дольше retention.
+// Блокируется до отмены ctx, поэтому запускается в отдельной горутине
+func RunTrashPurger(ctx context.Context, medicineService MedicineService, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := medicineService.PurgeExpiredTrash(ctx, retention)
+		if err != nil {
+			slog.ErrorContext(ctx, "Trash purge failed", "error", err)
+		} else if purged > 0 {
+			slog.InfoContext(ctx, "Purged medicines from trash", "purged", purged, "retention", retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Trash purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
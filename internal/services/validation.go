@@ -0,0 +1,69 @@
+package services
+
+import (
+	"math"
+	"pharmacy-api/internal/models"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ограничения на поля лекарства
+const (
+	maxMedicineNameLength        = 255
+	maxMedicineDescriptionLength = 2000
+	maxMedicineBarcodeLength     = 64
+)
+
+// FieldError - ошибка в значении конкретного поля
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError - данные не прошли проверку; Fields содержит ошибки по полям
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+" "+f.Message)
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// validateMedicine проверяет бизнес-правила для лекарства перед сохранением
+func validateMedicine(medicine models.Medicine) error {
+	if errs := medicineFieldErrors(medicine); len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+func medicineFieldErrors(medicine models.Medicine) []FieldError {
+	var errs []FieldError
+	if strings.TrimSpace(medicine.Name) == "" {
+		errs = append(errs, FieldError{Field: "name", Message: "is required"})
+	} else if utf8.RuneCountInString(medicine.Name) > maxMedicineNameLength {
+		errs = append(errs, FieldError{Field: "name", Message: "must be at most 255 characters"})
+	}
+	if utf8.RuneCountInString(medicine.Description) > maxMedicineDescriptionLength {
+		errs = append(errs, FieldError{Field: "description", Message: "must be at most 2000 characters"})
+	}
+	if utf8.RuneCountInString(medicine.Barcode) > maxMedicineBarcodeLength {
+		errs = append(errs, FieldError{Field: "barcode", Message: "must be at most 64 characters"})
+	}
+	if math.IsNaN(medicine.Price) || math.IsInf(medicine.Price, 0) {
+		errs = append(errs, FieldError{Field: "price", Message: "must be a number"})
+	} else if medicine.Price < 0 {
+		errs = append(errs, FieldError{Field: "price", Message: "must not be negative"})
+	}
+	if medicine.Quantity < 0 {
+		errs = append(errs, FieldError{Field: "quantity", Message: "must not be negative"})
+	}
+	if medicine.ReorderPoint < 0 {
+		errs = append(errs, FieldError{Field: "reorder_point", Message: "must not be negative"})
+	}
+	return errs
+}
@@ -0,0 +1,97 @@
+// Package tracing - трассировка OpenTelemetry: настройка экспорта спанов и передача
+// контекста трассировки (W3C traceparent) через заголовки сообщений Kafka
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName - имя трассировщика спанов приложения
+const instrumentationName = "pharmacy-api"
+
+// Экспортеры спанов
+const (
+	ExporterNone   = "none"   // спаны не записываются, контекст трассировки все равно передается дальше
+	ExporterStdout = "stdout" // спаны выводятся в stderr по одному JSON в строке, для локального запуска
+	ExporterOTLP   = "otlp"   // OTLP/HTTP в коллектор (Jaeger, Tempo, OpenTelemetry Collector)
+)
+
+// Config - настройки трассировки
+type Config struct {
+	Exporter    string
+	Endpoint    string // URL коллектора OTLP; пустой - по умолчанию http://localhost:4318 или OTEL_EXPORTER_OTLP_ENDPOINT
+	ServiceName string
+	SampleRatio float64 // доля записываемых трасс, если решение не принято вызывающим сервисом
+}
+
+// Setup настраивает глобальные TracerProvider и propagator и возвращает функцию,
+// которая отправляет оставшиеся спаны при остановке
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		// В stdout пишутся JSON-логи, спаны их не перемешивают
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer возвращает трассировщик приложения
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Inject записывает контекст трассировки из ctx в заголовки
+func Inject(ctx context.Context, headers map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+}
+
+// Extract возвращает ctx с контекстом трассировки из заголовков
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// Headers возвращает заголовки с контекстом трассировки ctx; пустые, если трассировки нет
+func Headers(ctx context.Context) map[string]string {
+	headers := make(map[string]string)
+	Inject(ctx, headers)
+	return headers
+}
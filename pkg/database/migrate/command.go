@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Usage - справка по командам Run
+const Usage = `  up            apply all pending migrations
+  down [N]      roll back the last N migrations (default 1)
+  to <version>  migrate up or down to the given version (0 rolls back everything)
+  status        list migrations and when they were applied`
+
+// Run выполняет команду миграций из аргументов командной строки (см. Usage); status пишется в out
+func (m *Migrator) Run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n%s", Usage)
+	}
+
+	switch command, rest := args[0], args[1:]; {
+	case command == "up" && len(rest) == 0:
+		return m.Up(ctx)
+	case command == "down" && len(rest) <= 1:
+		steps := 1
+		if len(rest) == 1 {
+			var err error
+			if steps, err = strconv.Atoi(rest[0]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", rest[0])
+			}
+		}
+		return m.Down(ctx, steps)
+	case command == "to" && len(rest) == 1:
+		version, err := strconv.ParseInt(rest[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", rest[0])
+		}
+		return m.To(ctx, version)
+	case command == "status" && len(rest) == 0:
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return writeStatus(out, statuses)
+	default:
+		return fmt.Errorf("invalid migrate command %q\n%s", strings.Join(args, " "), Usage)
+	}
+}
+
+// writeStatus выводит состояние миграций таблицей
+func writeStatus(out io.Writer, statuses []Status) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		name := s.Name
+		if s.Missing {
+			name = "(unknown to this build)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, name, applied)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,319 @@
+// Package migrate применяет версионированные SQL-миграции к PostgreSQL.
+// Примененные версии хранятся в таблице schema_migrations; на время работы берется
+// advisory lock, поэтому несколько реплик, запущенных одновременно, не применяют миграции параллельно
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// lockKey - ключ pg_advisory_lock, общий для всех экземпляров приложения
+const lockKey int64 = 7240375208519651
+
+// fileName - <версия>_<название>.(up|down).sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrNoDown - у миграции нет файла отката
+var ErrNoDown = errors.New("migration has no down file")
+
+// Migration - одна версия схемы
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string // пустая строка - откат не предусмотрен
+}
+
+// Status - миграция и время ее применения (nil - еще не применена)
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Missing   bool // версия применена, но файла миграции нет (база новее бинарника)
+}
+
+// Migrator применяет миграции
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logf       func(format string, args ...interface{})
+}
+
+// New читает миграции из корня fsys
+func New(db *sql.DB, fsys fs.FS, logf func(format string, args ...interface{})) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if logf == nil {
+		logf = func(string, ...interface{}) {}
+	}
+	return &Migrator{db: db, migrations: migrations, logf: logf}, nil
+}
+
+// load собирает пары up/down и сортирует их по версии
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q, expected <version>_<name>.(up|down).sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest возвращает последнюю известную версию (0 - миграций нет)
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up применяет все непримененные миграции. Версии новее известных не откатываются:
+// во время выкладки старая реплика может стартовать после того, как новая обновила схему
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.up(ctx, conn, applied, m.Latest())
+	})
+}
+
+// Down откатывает steps последних примененных миграций
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be at least 1, got %d", steps)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := sortedVersions(applied)
+		target := int64(0)
+		if steps < len(versions) {
+			target = versions[len(versions)-steps-1]
+		}
+		return m.down(ctx, conn, applied, target)
+	})
+}
+
+// To приводит схему к версии target: применяет миграции не новее target
+// и откатывает примененные миграции новее нее
+func (m *Migrator) To(ctx context.Context, target int64) error {
+	if target < 0 {
+		return fmt.Errorf("invalid target version %d", target)
+	}
+	if target > 0 && m.find(target) == nil {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.down(ctx, conn, applied, target); err != nil {
+			return err
+		}
+		return m.up(ctx, conn, applied, target)
+	})
+}
+
+// Status возвращает состояние всех известных и примененных миграций по возрастанию версии
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var result []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if at, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &at
+			}
+			result = append(result, status)
+		}
+		for version, at := range applied {
+			if m.find(version) == nil {
+				at := at
+				result = append(result, Status{Version: version, AppliedAt: &at, Missing: true})
+			}
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+		return nil
+	})
+	return result, err
+}
+
+// down откатывает примененные миграции новее target по убыванию версии
+func (m *Migrator) down(ctx context.Context, conn *sql.Conn, applied map[int64]time.Time, target int64) error {
+	versions := sortedVersions(applied)
+	for i := len(versions) - 1; i >= 0 && versions[i] > target; i-- {
+		migration := m.find(versions[i])
+		if migration == nil {
+			return fmt.Errorf("cannot roll back migration %d: its file is missing", versions[i])
+		}
+		if migration.Down == "" {
+			return fmt.Errorf("cannot roll back migration %d_%s: %w", migration.Version, migration.Name, ErrNoDown)
+		}
+		m.logf("Rolling back migration %d_%s", migration.Version, migration.Name)
+		if err := run(ctx, conn, migration.Down, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+			return fmt.Errorf("failed to roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// up применяет непримененные миграции не новее target по возрастанию версии
+func (m *Migrator) up(ctx context.Context, conn *sql.Conn, applied map[int64]time.Time, target int64) error {
+	for _, migration := range m.migrations {
+		if migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		m.logf("Applying migration %d_%s", migration.Version, migration.Name)
+		if err := run(ctx, conn, migration.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name); err != nil {
+			return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// run выполняет миграцию и запись в schema_migrations в одной транзакции
+func run(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Без аргументов запрос уходит простым протоколом, поэтому файл может содержать несколько команд
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// withLock выполняет fn на отдельном соединении, удерживая advisory lock.
+// Session-level lock привязан к соединению, поэтому все запросы идут через conn
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			m.logf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+// appliedVersions читает примененные версии
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// find возвращает миграцию по версии или nil
+func (m *Migrator) find(version int64) *Migration {
+	i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].Version >= version })
+	if i < len(m.migrations) && m.migrations[i].Version == version {
+		return &m.migrations[i]
+	}
+	return nil
+}
+
+// sortedVersions возвращает версии по возрастанию
+func sortedVersions(applied map[int64]time.Time) []int64 {
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "time"
+
+    "gorm.io/gorm"
+    "gorm.io/gorm/logger"
+)
+
+// slogLogger пишет логи GORM через log/slog. Записи делаются с context запроса,
+// поэтому к ним добавляются атрибуты запроса (request_id, user_id и т.д.)
+type slogLogger struct {
+    level         logger.LogLevel
+    slowThreshold time.Duration
+}
+
+// newSlogLogger создает логгер GORM: ошибки - error, медленные запросы - warn, все запросы (уровень info) - info
+func newSlogLogger(level logger.LogLevel, slowThreshold time.Duration) logger.Interface {
+    return &slogLogger{level: level, slowThreshold: slowThreshold}
+}
+
+func (l *slogLogger) LogMode(level logger.LogLevel) logger.Interface {
+    copied := *l
+    copied.level = level
+    return &copied
+}
+
+func (l *slogLogger) Info(ctx context.Context, format string, args ...interface{}) {
+    if l.level >= logger.Info {
+        slog.InfoContext(ctx, fmt.Sprintf(format, args...))
+    }
+}
+
+func (l *slogLogger) Warn(ctx context.Context, format string, args ...interface{}) {
+    if l.level >= logger.Warn {
+        slog.WarnContext(ctx, fmt.Sprintf(format, args...))
+    }
+}
+
+func (l *slogLogger) Error(ctx context.Context, format string, args ...interface{}) {
+    if l.level >= logger.Error {
+        slog.ErrorContext(ctx, fmt.Sprintf(format, args...))
+    }
+}
+
+// Trace логирует выполненный запрос
+func (l *slogLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+    if l.level <= logger.Silent {
+        return
+    }
+    elapsed := time.Since(begin)
+    durationMS := float64(elapsed.Microseconds()) / 1000
+    switch {
+    case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound): // "не найдено" - обычный результат
+        sql, rows := fc()
+        slog.ErrorContext(ctx, "SQL query failed", "error", err, "sql", sql, "rows", rows, "duration_ms", durationMS)
+    case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+        sql, rows := fc()
+        slog.WarnContext(ctx, "Slow SQL query", "sql", sql, "rows", rows, "duration_ms", durationMS, "threshold", l.slowThreshold.String())
+    case l.level >= logger.Info:
+        sql, rows := fc()
+        slog.InfoContext(ctx, "SQL query", "sql", sql, "rows", rows, "duration_ms", durationMS)
+    }
+}
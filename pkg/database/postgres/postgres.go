@@ -1,9 +1,9 @@
 package postgres
 
 import (
+    "context"
     "fmt"
-    "log"
-    "os"
+    "log/slog"
     "time"
 
     "gorm.io/driver/postgres"
@@ -11,32 +11,117 @@ import (
     "gorm.io/gorm/logger"
 )
 
-func NewPostgresDB() (*gorm.DB, error) {
-    dsn := os.Getenv("DATABASE_URL")
+// Options - настройки пула соединений, логирования SQL и подключения при старте
+type Options struct {
+    MaxOpenConns    int           // 0 - без ограничения
+    MaxIdleConns    int
+    ConnMaxLifetime time.Duration // 0 - соединения не пересоздаются
+    ConnMaxIdleTime time.Duration
+    LogLevel        string        // silent, error, warn или info (все запросы)
+    SlowThreshold   time.Duration // запросы дольше логируются на уровне warn; 0 - не логировать
+    ConnectTimeout  time.Duration // сколько пытаться подключиться при старте; 0 - одна попытка
+}
+
+// DefaultOptions - настройки по умолчанию
+var DefaultOptions = Options{
+    MaxOpenConns:    25,
+    MaxIdleConns:    10,
+    ConnMaxLifetime: 30 * time.Minute,
+    ConnMaxIdleTime: 5 * time.Minute,
+    LogLevel:        "warn",
+    SlowThreshold:   200 * time.Millisecond,
+    ConnectTimeout:  time.Minute,
+}
+
+// Задержки между попытками подключения
+const (
+    connectMinBackoff = 500 * time.Millisecond
+    connectMaxBackoff = 5 * time.Second
+)
+
+// ParseLogLevel разбирает уровень логирования SQL
+func ParseLogLevel(level string) (logger.LogLevel, error) {
+    switch level {
+    case "silent":
+        return logger.Silent, nil
+    case "error":
+        return logger.Error, nil
+    case "warn":
+        return logger.Warn, nil
+    case "info":
+        return logger.Info, nil
+    }
+    return 0, fmt.Errorf("unknown log level %q (use silent, error, warn or info)", level)
+}
+
+// NewPostgresDB подключается к PostgreSQL по строке подключения dsn.
+// Пока база недоступна (например, контейнер еще стартует), подключение повторяется
+// с экспоненциальной задержкой, но не дольше opts.ConnectTimeout и не после отмены ctx
+func NewPostgresDB(ctx context.Context, dsn string, opts Options) (*gorm.DB, error) {
     if dsn == "" {
-        return nil, fmt.Errorf("DATABASE_URL not set in .env")
+        return nil, fmt.Errorf("database connection string is empty")
+    }
+    logLevel, err := ParseLogLevel(opts.LogLevel)
+    if err != nil {
+        return nil, err
     }
 
-    db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-        Logger: logger.Default.LogMode(logger.Info), // Показывать SQL запросы
+    config := &gorm.Config{
+        Logger: newSlogLogger(logLevel, opts.SlowThreshold),
         NowFunc: func() time.Time {
             return time.Now().UTC() // Устанавливаем UTC
         },
         PrepareStmt: true, // Кеширование подготовленных операторов
-    })
-    if err != nil {
-        log.Printf("NewPostgresDB: Failed to connect to database: %v", err)
-        return nil, err
+        TranslateError: true, // Нарушение уникальности -> gorm.ErrDuplicatedKey
     }
 
-    log.Println("Connected to database")
-    return db, nil
+    deadline := time.Now().Add(opts.ConnectTimeout)
+    backoff := connectMinBackoff
+    for attempt := 1; ; attempt++ {
+        db, err := gorm.Open(postgres.Open(dsn), config)
+        if err == nil {
+            if err := configurePool(db, opts); err != nil {
+                return nil, err
+            }
+            slog.InfoContext(ctx, "Connected to database", "attempts", attempt)
+            return db, nil
+        }
+        closeDB(db)
+
+        if time.Now().Add(backoff).After(deadline) {
+            return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", attempt, err)
+        }
+        slog.WarnContext(ctx, "Database is not available, retrying", "attempt", attempt, "retry_in", backoff, "error", err)
+        select {
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        case <-time.After(backoff):
+        }
+        if backoff *= 2; backoff > connectMaxBackoff {
+            backoff = connectMaxBackoff
+        }
+    }
 }
 
-func AutoMigrate(db *gorm.DB, models ...interface{}) error {
-    err := db.AutoMigrate(models...)
+// configurePool применяет настройки пула соединений
+func configurePool(db *gorm.DB, opts Options) error {
+    sqlDB, err := db.DB()
     if err != nil {
-        log.Printf("AutoMigrate: Failed to auto migrate: %v", err)
+        return err
     }
-    return err
-}
\ No newline at end of file
+    sqlDB.SetMaxOpenConns(opts.MaxOpenConns)
+    sqlDB.SetMaxIdleConns(opts.MaxIdleConns)
+    sqlDB.SetConnMaxLifetime(opts.ConnMaxLifetime)
+    sqlDB.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+    return nil
+}
+
+// closeDB закрывает пул неудачной попытки подключения
+func closeDB(db *gorm.DB) {
+    if db == nil {
+        return
+    }
+    if sqlDB, err := db.DB(); err == nil {
+        sqlDB.Close()
+    }
+}
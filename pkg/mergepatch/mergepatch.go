@@ -0,0 +1,60 @@
+// Package mergepatch реализует JSON Merge Patch (RFC 7396)
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ErrNotObject - патч или документ не является JSON-объектом
+var ErrNotObject = errors.New("merge patch must be a JSON object")
+
+// Apply применяет патч к документу и возвращает результат.
+// Поля со значением null удаляются, объекты сливаются рекурсивно, остальные значения заменяются
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := decode(doc, &target); err != nil {
+		return nil, err
+	}
+	var p interface{}
+	if err := decode(patch, &p); err != nil {
+		return nil, err
+	}
+	if _, ok := p.(map[string]interface{}); !ok {
+		return nil, ErrNotObject
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+			continue
+		}
+		targetObj[name] = merge(targetObj[name], value)
+	}
+	return targetObj
+}
+
+// decode сохраняет числа как json.Number, чтобы не терять точность
+func decode(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package mergepatch
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Примеры из приложения A RFC 7396 и случаи, важные для PATCH лекарств
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{name: "replace value", doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "null removes member", doc: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{name: "null removes only that member", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "array replaced", doc: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "value replaced by array", doc: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{name: "nested merge and removal", doc: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{name: "arrays not merged", doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{name: "null in document kept", doc: `{"e":null}`, patch: `{"a":1}`, want: `{"e":null,"a":1}`},
+		{name: "nested null in new object dropped", doc: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+		{name: "empty patch", doc: `{"a":"b"}`, patch: `{}`, want: `{"a":"b"}`},
+		{name: "large integer keeps precision", doc: `{"n":1}`, patch: `{"n":9007199254740993}`, want: `{"n":9007199254740993}`},
+		{name: "null for missing member", doc: `{"a":1}`, patch: `{"b":null}`, want: `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(t, got, []byte(tt.want)) {
+				t.Errorf("Apply(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		wantErr error
+	}{
+		{name: "patch is array", doc: `{}`, patch: `[1]`, wantErr: ErrNotObject},
+		{name: "patch is null", doc: `{}`, patch: `null`, wantErr: ErrNotObject},
+		{name: "patch is string", doc: `{}`, patch: `"a"`, wantErr: ErrNotObject},
+		{name: "invalid patch", doc: `{}`, patch: `{"a":`},
+		{name: "trailing data", doc: `{}`, patch: `{"a":1} {"b":2}`},
+		{name: "invalid document", doc: `{`, patch: `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb interface{}
+	if err := decode(a, &va); err != nil {
+		t.Fatal(err)
+	}
+	if err := decode(b, &vb); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(va, vb)
+}